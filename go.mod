@@ -4,11 +4,19 @@ go 1.15
 
 require (
 	github.com/golang/protobuf v1.4.3
+	github.com/lib/pq v1.10.9
+	github.com/nats-io/nats.go v1.31.0
 	github.com/prometheus/client_golang v1.9.0
+	github.com/redis/go-redis/v9 v9.7.0
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/rs/zerolog v1.20.0
+	github.com/segmentio/kafka-go v0.4.47
 	github.com/streadway/amqp v1.0.0
 	github.com/valyala/fasthttp v1.18.0
+	go.etcd.io/bbolt v1.3.7
+	golang.org/x/time v0.0.0-20191024005414-555d28b269f0
+	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013
 	google.golang.org/grpc v1.34.0
 	google.golang.org/protobuf v1.25.0
-	gopkg.in/yaml.v3 v3.0.0-20200615113413-eeeca48fe776
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,139 @@
+package embedded
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/internal/jobs"
+	bolt "go.etcd.io/bbolt"
+)
+
+// OpenTimeout constant. Another process holding the file makes open fail after this.
+const OpenTimeout = 5 * time.Second
+
+// jobsBucket is keyed by the job ID.
+var jobsBucket = []byte("jobs")
+
+// Embedded keeps the jobs on a bbolt database file. The file can be opened by one process at a time, so it can not
+// be the file of the embedded broker.
+type Embedded struct {
+	path string
+	ttl  time.Duration
+	mu   sync.Mutex
+	db   *bolt.DB
+}
+
+// New ctor. The jobs not updated for the ttl are removed.
+func New(path string, ttl time.Duration) *Embedded {
+	e := &Embedded{path: path, ttl: ttl}
+	go e.expire()
+	return e
+}
+
+// Save .
+func (e *Embedded) Save(job *jobs.Job) error {
+	db, err := e.connection()
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Put([]byte(job.ID), value)
+	})
+}
+
+// Get .
+func (e *Embedded) Get(id string) (*jobs.Job, error) {
+	db, err := e.connection()
+	if err != nil {
+		return nil, err
+	}
+	var job *jobs.Job
+	err = db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(jobsBucket).Get([]byte(id))
+		if value == nil {
+			return nil
+		}
+		job = &jobs.Job{}
+		return json.Unmarshal(value, job)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || time.Since(job.UpdatedAt) > e.ttl {
+		return nil, jobs.ErrNotFound
+	}
+	return job, nil
+}
+
+// Delete .
+func (e *Embedded) Delete(id string) error {
+	db, err := e.connection()
+	if err != nil {
+		return err
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Delete([]byte(id))
+	})
+}
+
+func (e *Embedded) connection() (*bolt.DB, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.db != nil {
+		return e.db, nil
+	}
+	db, err := bolt.Open(e.path, 0600, &bolt.Options{Timeout: OpenTimeout})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(jobsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	e.db = db
+	return e.db, nil
+}
+
+func (e *Embedded) expire() {
+	for range time.Tick(time.Minute) {
+		db, err := e.connection()
+		if err != nil {
+			log.Error().Err(err).Str("path", e.path).Msg("failed to open the job store")
+			continue
+		}
+		err = db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket(jobsBucket)
+			// deleting on the cursor skips the next key, so the keys are deleted after the iteration.
+			var expired [][]byte
+			err := b.ForEach(func(k, v []byte) error {
+				var job jobs.Job
+				if err := json.Unmarshal(v, &job); err != nil || time.Since(job.UpdatedAt) > e.ttl {
+					expired = append(expired, k)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, k := range expired {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Error().Err(err).Str("path", e.path).Msg("failed to remove the expired jobs")
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// Queued is the status of a job waiting on its queue.
+	Queued = "queued"
+	// InProgress is the status of a job sent to the worker.
+	InProgress = "in_progress"
+	// Succeeded is the status of a job the worker handled.
+	Succeeded = "succeeded"
+	// Failed is the status of a job dropped after a failure that is not retried.
+	Failed = "failed"
+	// Retrying is the status of a failed job waiting for its next attempt.
+	Retrying = "retrying"
+	// Dead is the status of a dead lettered job.
+	Dead = "dead"
+	// Revoked is the status of a cancelled job. Its deliveries are acked without calling the worker.
+	Revoked = "revoked"
+)
+
+var (
+	// ErrNotFound is returned if the job is unknown or expired.
+	ErrNotFound = errors.New("the job is not found.")
+	// ErrNotRevocable is returned if the job is in progress or finished.
+	ErrNotRevocable = errors.New("only the queued and the retrying jobs can be revoked.")
+)
+
+// Job is the last known state of a queued message.
+type Job struct {
+	ID        string
+	Name      string
+	Status    string
+	Attempt   int
+	Error     string
+	QueuedAt  time.Time
+	UpdatedAt time.Time
+}
+
+// Store keeps the jobs until they expire. The finished and the unfinished jobs expire the same way.
+type Store interface {
+	// Save creates or replaces the job.
+	Save(job *Job) error
+	// Get returns ErrNotFound if the job is unknown or expired.
+	Get(id string) (*Job, error)
+	// Delete removes the job, if any.
+	Delete(id string) error
+}
+
+// Revoke marks the queued or the retrying job as revoked. Revoking a revoked job again changes nothing. A job that
+// starts its attempt at the same time may still be worked, the store can not tell which came first.
+func Revoke(store Store, id string) (*Job, error) {
+	job, err := store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	switch job.Status {
+	case Revoked:
+		return job, nil
+	case Queued, Retrying:
+	default:
+		return nil, ErrNotRevocable
+	}
+	job.Status = Revoked
+	job.UpdatedAt = time.Now()
+	if err := store.Save(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
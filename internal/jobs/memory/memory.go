@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/turgayozgur/messageman/internal/jobs"
+)
+
+// Memory keeps the jobs in process. Only the jobs queued and worked by this instance are tracked completely.
+type Memory struct {
+	ttl  time.Duration
+	mu   sync.Mutex
+	jobs map[string]*jobs.Job
+}
+
+// New ctor. The jobs not updated for the ttl are removed.
+func New(ttl time.Duration) *Memory {
+	m := &Memory{ttl: ttl, jobs: make(map[string]*jobs.Job)}
+	go m.expire()
+	return m
+}
+
+// Save .
+func (m *Memory) Save(job *jobs.Job) error {
+	j := *job
+	m.mu.Lock()
+	m.jobs[job.ID] = &j
+	m.mu.Unlock()
+	return nil
+}
+
+// Get .
+func (m *Memory) Get(id string) (*jobs.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok || time.Since(job.UpdatedAt) > m.ttl {
+		return nil, jobs.ErrNotFound
+	}
+	j := *job
+	return &j, nil
+}
+
+// Delete .
+func (m *Memory) Delete(id string) error {
+	m.mu.Lock()
+	delete(m.jobs, id)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) expire() {
+	for range time.Tick(time.Minute) {
+		m.mu.Lock()
+		for id, job := range m.jobs {
+			if time.Since(job.UpdatedAt) > m.ttl {
+				delete(m.jobs, id)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
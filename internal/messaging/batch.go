@@ -0,0 +1,162 @@
+package messaging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/turgayozgur/messageman/config"
+)
+
+// Batcher collects the deliveries handled at the same time into batches. Every delivery waits for its batch to be
+// sent, so a batch fills up only if the consumer has as many handlers as the batch size.
+type Batcher struct {
+	size int
+	wait time.Duration
+	send func(items []*BatchItem)
+
+	mu      sync.Mutex
+	pending []*BatchItem
+	timer   *time.Timer
+	// generation tells the timer of a batch that is sent already apart from the one of the pending batch.
+	generation int
+}
+
+// BatchItem is a delivery of a batch, the send function sets its failure.
+type BatchItem struct {
+	Delivery *Delivery
+	Failure  *Failure
+	done     chan struct{}
+}
+
+// NewBatcher creates the batcher that sends the batches with the given function.
+func NewBatcher(cfg *config.BatchConfig, send func(items []*BatchItem)) *Batcher {
+	wait := cfg.WaitMs
+	if wait <= 0 {
+		wait = config.DefaultBatchWaitMs
+	}
+	return &Batcher{size: cfg.Size, wait: time.Duration(wait) * time.Millisecond, send: send}
+}
+
+// Do adds the delivery to the pending batch and returns the failure of the delivery once the batch is sent.
+func (b *Batcher) Do(d *Delivery) *Failure {
+	item := &BatchItem{Delivery: d, done: make(chan struct{})}
+	b.mu.Lock()
+	b.pending = append(b.pending, item)
+	if len(b.pending) >= b.size {
+		items := b.take()
+		b.mu.Unlock()
+		b.flush(items)
+	} else {
+		if len(b.pending) == 1 {
+			generation := b.generation
+			b.timer = time.AfterFunc(b.wait, func() {
+				b.mu.Lock()
+				if b.generation != generation {
+					b.mu.Unlock()
+					return
+				}
+				items := b.take()
+				b.mu.Unlock()
+				b.flush(items)
+			})
+		}
+		b.mu.Unlock()
+	}
+	<-item.done
+	return item.Failure
+}
+
+// take must be called with the lock held.
+func (b *Batcher) take() []*BatchItem {
+	items := b.pending
+	b.pending = nil
+	b.generation++
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return items
+}
+
+func (b *Batcher) flush(items []*BatchItem) {
+	b.send(items)
+	for _, i := range items {
+		close(i.done)
+	}
+}
+
+// batchItemModel is an item of the JSON array posted to a REST worker.
+type batchItemModel struct {
+	Body    json.RawMessage   `json:"body"`
+	Headers map[string]string `json:"headers"`
+}
+
+// batchResponseModel is the response body of a REST worker. The items without a result are retried.
+type batchResponseModel struct {
+	Results []struct {
+		Status int    `json:"status"`
+		Error  string `json:"error"`
+	} `json:"results"`
+}
+
+// doRestBatch posts the bodies as a JSON array. A body that is not JSON is sent as a JSON string.
+func doRestBatch(wrapper Wrapper, client *http.Client, url string, messages [][]byte) (*http.Response, error) {
+	items := make([]*batchItemModel, 0, len(messages))
+	for _, m := range messages {
+		body, headers, err := wrapper.Unwrap(m)
+		if err != nil {
+			return nil, err
+		}
+		item := &batchItemModel{Body: body, Headers: make(map[string]string, len(headers))}
+		if !json.Valid(body) {
+			if item.Body, err = json.Marshal(string(body)); err != nil {
+				return nil, err
+			}
+		}
+		for k, v := range headers {
+			item.Headers[k] = string(v)
+		}
+		items = append(items, item)
+	}
+	b, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	return client.Do(req)
+}
+
+// restBatchFailures reads the failures of the items from the response body of a successful batch. The items
+// without a result, all of them on an empty body, fail to be retried.
+func restBatchFailures(response *http.Response, count int) ([]*Failure, error) {
+	defer response.Body.Close()
+	var model batchResponseModel
+	if err := json.NewDecoder(response.Body).Decode(&model); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read the results of the batch. %v", err)
+	}
+	failures := make([]*Failure, count)
+	for n := range failures {
+		if n >= len(model.Results) {
+			failures[n] = noBatchResult()
+			continue
+		}
+		if r := model.Results[n]; r.Status >= 300 {
+			failures[n] = &Failure{StatusCode: r.Status, Error: r.Error}
+		}
+	}
+	return failures, nil
+}
+
+// noBatchResult is the failure of an item the worker returned no result for. It has no status, so it is retried.
+func noBatchResult() *Failure {
+	return &Failure{Error: "no result for the item in the batch response"}
+}
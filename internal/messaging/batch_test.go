@@ -0,0 +1,204 @@
+package messaging
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/turgayozgur/messageman/config"
+)
+
+// batches records the sizes of the batches sent and fails the items with a "fail" body.
+type batches struct {
+	mu    sync.Mutex
+	sizes []int
+}
+
+func (b *batches) send(items []*BatchItem) {
+	b.mu.Lock()
+	b.sizes = append(b.sizes, len(items))
+	b.mu.Unlock()
+	for _, i := range items {
+		if string(i.Delivery.Body) == "fail" {
+			i.Failure = &Failure{StatusCode: 422, Error: "failed"}
+		}
+	}
+}
+
+// doAll hands the bodies to the batcher at the same time and returns their failures in order.
+func doAll(b *Batcher, bodies ...string) []*Failure {
+	failures := make([]*Failure, len(bodies))
+	var wg sync.WaitGroup
+	for n, body := range bodies {
+		wg.Add(1)
+		go func(n int, body string) {
+			defer wg.Done()
+			failures[n] = b.Do(&Delivery{Body: []byte(body), Attempt: 1})
+		}(n, body)
+	}
+	wg.Wait()
+	return failures
+}
+
+func TestBatcherFull(t *testing.T) {
+	sent := &batches{}
+	// the wait is longer than the test, a batch is sent only once it is full.
+	b := NewBatcher(&config.BatchConfig{Size: 3, WaitMs: 60000}, sent.send)
+	start := time.Now()
+	failures := doAll(b, "ok", "fail", "ok")
+	if time.Since(start) > 10*time.Second {
+		t.Fatal("the full batch waited")
+	}
+	if len(sent.sizes) != 1 || sent.sizes[0] != 3 {
+		t.Fatalf("sent batches of %v, want one of 3", sent.sizes)
+	}
+	fails := 0
+	for _, f := range failures {
+		if f != nil {
+			fails++
+			if f.StatusCode != 422 {
+				t.Errorf("unexpected failure %+v", f)
+			}
+		}
+	}
+	if fails != 1 {
+		t.Errorf("%d items failed, want 1", fails)
+	}
+}
+
+func TestBatcherWait(t *testing.T) {
+	sent := &batches{}
+	b := NewBatcher(&config.BatchConfig{Size: 10, WaitMs: 20}, sent.send)
+	start := time.Now()
+	failures := doAll(b, "ok", "ok")
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("the batch is sent after %v, before the wait", elapsed)
+	}
+	if len(sent.sizes) != 1 || sent.sizes[0] != 2 {
+		t.Fatalf("sent batches of %v, want one of 2", sent.sizes)
+	}
+	for _, f := range failures {
+		if f != nil {
+			t.Errorf("unexpected failure %+v", f)
+		}
+	}
+	// the next delivery starts a new batch with its own wait.
+	if f := b.Do(&Delivery{Body: []byte("fail")}); f == nil {
+		t.Error("expected the failure of the item")
+	}
+	if len(sent.sizes) != 2 || sent.sizes[1] != 1 {
+		t.Fatalf("sent batches of %v, want a second one of 1", sent.sizes)
+	}
+}
+
+func TestBatcherSplit(t *testing.T) {
+	sent := &batches{}
+	b := NewBatcher(&config.BatchConfig{Size: 2, WaitMs: 20}, sent.send)
+	doAll(b, "1", "2", "3", "4", "5")
+	total := 0
+	for _, size := range sent.sizes {
+		if size > 2 {
+			t.Errorf("sent a batch of %d, larger than the size", size)
+		}
+		total += size
+	}
+	if total != 5 || len(sent.sizes) < 3 {
+		t.Errorf("sent batches of %v, want 5 items in at least 3 batches", sent.sizes)
+	}
+}
+
+func response(body string) *http.Response {
+	return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}
+}
+
+func TestRestBatchFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		failures []int // the status codes of the failures, 0 if succeeded, -1 if without a result.
+	}{
+		{"all results", `{"results":[{"status":200},{"status":422,"error":"bad"},{"status":503}]}`, []int{0, 422, 503}},
+		{"fewer results", `{"results":[{"status":200}]}`, []int{0, -1, -1}},
+		{"more results", `{"results":[{"status":200},{"status":200},{"status":200},{"status":500}]}`, []int{0, 0, 0}},
+		{"no results", `{}`, []int{-1, -1, -1}},
+		{"empty body", ``, []int{-1, -1, -1}},
+		{"redirect status", `{"results":[{"status":301},{"status":299},{"status":0}]}`, []int{301, 0, 0}},
+	}
+	failure := NewFailurePolicy(nil)
+	for _, tt := range tests {
+		failures, err := restBatchFailures(response(tt.body), len(tt.failures))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		for n, want := range tt.failures {
+			f := failures[n]
+			switch {
+			case want == 0 && f != nil:
+				t.Errorf("%s: item %d failed with %+v", tt.name, n, f)
+			case want == -1 && (f == nil || f.StatusCode != 0 || !failure.Retryable(f)):
+				t.Errorf("%s: item %d without a result is %+v, want a retryable failure", tt.name, n, f)
+			case want > 0 && (f == nil || f.StatusCode != want):
+				t.Errorf("%s: item %d is %+v, want the status %d", tt.name, n, f, want)
+			}
+		}
+	}
+	if _, err := restBatchFailures(response(`{"results":`), 1); err == nil {
+		t.Error("expected an error on a body that is not the results")
+	}
+}
+
+func TestDoRestBatch(t *testing.T) {
+	var posted []map[string]interface{}
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&posted); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	w := &DefaultWrapper{}
+	var messages [][]byte
+	for _, m := range []struct {
+		body    string
+		headers map[string][]byte
+	}{
+		{`{"id":1}`, map[string][]byte{"x-tenant": []byte("acme")}},
+		{`plain text`, nil},
+	} {
+		message, err := w.Wrap([]byte(m.body), m.headers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, message)
+	}
+	res, err := doRestBatch(w, server.Client(), server.URL, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if contentType != ContentType {
+		t.Errorf("the content type is %s", contentType)
+	}
+	if len(posted) != 2 {
+		t.Fatalf("posted %d items, want 2", len(posted))
+	}
+	if body, _ := json.Marshal(posted[0]["body"]); string(body) != `{"id":1}` {
+		t.Errorf("the first body is %s", body)
+	}
+	if headers, _ := posted[0]["headers"].(map[string]interface{}); headers["x-tenant"] != "acme" {
+		t.Errorf("the first headers are %v", posted[0]["headers"])
+	}
+	// a body that is not JSON is a JSON string.
+	if posted[1]["body"] != "plain text" {
+		t.Errorf("the second body is %v", posted[1]["body"])
+	}
+}
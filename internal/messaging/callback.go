@@ -0,0 +1,99 @@
+package messaging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// CallbackURLHeader is the URL the result of the job is posted to.
+	CallbackURLHeader = "x-callback-url"
+	// CallbackEventHeader is the event the result of the job is published as.
+	CallbackEventHeader = "x-callback-event"
+)
+
+// CallbackModel is the result of a finished job, posted to the callback URL or published as the callback event.
+type CallbackModel struct {
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// StatusCode is the HTTP or the gRPC status code of the last attempt. 0 if no response received.
+	StatusCode int `json:"statusCode"`
+	// Body is the response of the worker. A response that is not JSON is a JSON string.
+	Body    json.RawMessage `json:"body,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Attempt int             `json:"attempt"`
+}
+
+// sendCallback sends the result of the finished job to the callback URL and the callback event of the message, if any.
+// r is the response of the worker if the job succeeded. The callbacks are not retried.
+func sendCallback(m Messager, w Wrapper, client *http.Client, service string, name string, d *Delivery, r *Reply, result Result) {
+	_, headers, err := w.Unwrap(d.Body)
+	url, event := string(headers[CallbackURLHeader]), string(headers[CallbackEventHeader])
+	if err != nil || (url == "" && event == "") {
+		return
+	}
+	model := &CallbackModel{ID: string(headers[JobIDHeader]), Name: name, Status: jobStatus(result), Attempt: d.Attempt}
+	if f := result.Failure; f != nil {
+		model.StatusCode, model.Error = f.StatusCode, f.Error
+	} else if r != nil {
+		model.StatusCode = r.StatusCode
+		if len(r.Body) > 0 {
+			model.Body = r.Body
+			if !json.Valid(r.Body) {
+				model.Body, _ = json.Marshal(string(r.Body))
+			}
+		}
+	}
+	body, err := json.Marshal(model)
+	if err != nil {
+		log.Error().Err(err).Str("service", service).Str("name", name).Msg("failed to create the callback")
+		return
+	}
+	l := log.Error().Str("body", string(body)).Str("service", service).Str("name", name)
+	if url != "" {
+		if err := postCallback(client, url, body); err != nil {
+			l.Msgf("callback failed. url:%s %v", url, err)
+		}
+	}
+	if event != "" {
+		if err := publishCallback(m, w, service, event, body, headers); err == ErrUnroutable {
+			log.Warn().Str("name", event).Msg("callback published to no subscribers")
+		} else if err != nil {
+			l.Msgf("callback failed. event:%s %v", event, err)
+		}
+	}
+}
+
+func postCallback(client *http.Client, url string, body []byte) error {
+	response, err := client.Post(url, ContentType, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("non success status code %d", response.StatusCode)
+	}
+	return nil
+}
+
+// publishCallback publishes the result with the headers of the job, except the callback and the reply headers.
+func publishCallback(m Messager, w Wrapper, service string, event string, body []byte, headers map[string][]byte) error {
+	h := make(map[string][]byte, len(headers))
+	for k, v := range headers {
+		switch k {
+		case CallbackURLHeader, CallbackEventHeader, ReplyToHeader, CorrelationIDHeader:
+		default:
+			h[k] = v
+		}
+	}
+	message, err := w.Wrap(body, h)
+	if err != nil {
+		return err
+	}
+	return m.Publish(service, event, message)
+}
@@ -0,0 +1,91 @@
+package messaging
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/config"
+)
+
+// CronRegistrar queues or publishes the payload of a schedule on every tick of its cron expression.
+type CronRegistrar struct {
+	messager Messager
+	cfg      *config.ScheduleConfig
+	schedule cron.Schedule
+	location *time.Location
+	message  []byte
+}
+
+// NewCronRegistrar validates the schedule and wraps its payload once for all ticks.
+func NewCronRegistrar(m Messager, w Wrapper, cfg *config.ScheduleConfig) (*CronRegistrar, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("the name of the schedule is required")
+	}
+	if (cfg.Queue == "") == (cfg.Event == "") {
+		return nil, fmt.Errorf("one of the queue and the event of the schedule %s is required", cfg.Name)
+	}
+	schedule, err := cron.ParseStandard(cfg.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression of the schedule %s. %v", cfg.Name, err)
+	}
+	location := time.UTC
+	if cfg.Timezone != "" {
+		if location, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone of the schedule %s. %v", cfg.Name, err)
+		}
+	}
+	headers := make(map[string][]byte, len(cfg.Headers))
+	for k, v := range cfg.Headers {
+		headers[k] = []byte(v)
+	}
+	message, err := w.Wrap([]byte(cfg.Payload), headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap the payload of the schedule %s. %v", cfg.Name, err)
+	}
+	return &CronRegistrar{
+		messager: m,
+		cfg:      cfg,
+		schedule: schedule,
+		location: location,
+		message:  message,
+	}, nil
+}
+
+// RegisterCron starts firing the ticks. Missed ticks, like the ones while messageman is down, are not fired later.
+func (cr *CronRegistrar) RegisterCron() {
+	go func() {
+		for {
+			tick := cr.schedule.Next(time.Now().In(cr.location))
+			time.Sleep(time.Until(tick))
+			cr.fire(tick)
+		}
+	}()
+	log.Info().Str("name", cr.cfg.Name).Str("cron", cr.cfg.Cron).Msg("schedule registered")
+}
+
+func (cr *CronRegistrar) fire(tick time.Time) {
+	if locker, ok := cr.messager.(TickLocker); ok {
+		locked, err := locker.LockTick(cr.cfg.Name, tick)
+		if err != nil {
+			log.Error().Str("name", cr.cfg.Name).Msgf("failed to lock the tick, skipped. %v", err)
+			return
+		}
+		if !locked {
+			log.Debug().Str("name", cr.cfg.Name).Msg("tick fired by another instance")
+			return
+		}
+	}
+	var err error
+	if cr.cfg.Queue != "" {
+		err = cr.messager.Queue(cr.cfg.Name, cr.cfg.Queue, cr.message)
+	} else {
+		err = cr.messager.Publish(cr.cfg.Name, cr.cfg.Event, cr.message)
+	}
+	if err != nil {
+		log.Error().Str("name", cr.cfg.Name).Msgf("failed to fire the tick. %v", err)
+		return
+	}
+	log.Debug().Str("name", cr.cfg.Name).Time("tick", tick).Msg("tick fired")
+}
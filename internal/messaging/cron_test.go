@@ -0,0 +1,173 @@
+package messaging
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	// the timezones of the tests are found without the zoneinfo of the system.
+	_ "time/tzdata"
+
+	"github.com/turgayozgur/messageman/config"
+)
+
+// sent is a message queued or published on the recorder.
+type sent struct {
+	service string
+	name    string
+	message []byte
+	pubSub  bool
+}
+
+// recorder is a Messager that keeps the messages sent to it. The consumers are never called.
+type recorder struct {
+	mu   sync.Mutex
+	sent []sent
+	err  error
+}
+
+func (r *recorder) EnsureCanConnect() bool                   { return true }
+func (r *recorder) NotifyRecover(ch chan string) chan string { return ch }
+
+func (r *recorder) Queue(service string, name string, message []byte) error {
+	return r.send(service, name, message, false)
+}
+
+func (r *recorder) Publish(service string, name string, message []byte) error {
+	return r.send(service, name, message, true)
+}
+
+func (r *recorder) Work(service string, name string, callback Callback, opts ConsumeOptions) error {
+	return nil
+}
+
+func (r *recorder) Subscribe(service string, name string, callback Callback, opts ConsumeOptions) error {
+	return nil
+}
+
+func (r *recorder) send(service string, name string, message []byte, pubSub bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.sent = append(r.sent, sent{service: service, name: name, message: message, pubSub: pubSub})
+	return nil
+}
+
+// lockingRecorder fires the ticks it locks only.
+type lockingRecorder struct {
+	recorder
+	locked map[time.Time]bool
+}
+
+func (r *lockingRecorder) LockTick(name string, tick time.Time) (bool, error) {
+	if r.locked[tick] {
+		return false, nil
+	}
+	r.locked[tick] = true
+	return true, nil
+}
+
+func TestNewCronRegistrarErrors(t *testing.T) {
+	tests := []struct {
+		cfg *config.ScheduleConfig
+		err string
+	}{
+		{&config.ScheduleConfig{Cron: "* * * * *", Queue: "q"}, "the name of the schedule is required"},
+		{&config.ScheduleConfig{Name: "s", Cron: "* * * * *"}, "one of the queue and the event"},
+		{&config.ScheduleConfig{Name: "s", Cron: "* * * * *", Queue: "q", Event: "e"}, "one of the queue and the event"},
+		{&config.ScheduleConfig{Name: "s", Cron: "* * *", Queue: "q"}, "invalid cron expression"},
+		{&config.ScheduleConfig{Name: "s", Cron: "61 * * * *", Queue: "q"}, "invalid cron expression"},
+		{&config.ScheduleConfig{Name: "s", Cron: "* * * * *", Queue: "q", Timezone: "Nowhere/City"}, "invalid timezone"},
+	}
+	for _, tt := range tests {
+		_, err := NewCronRegistrar(&recorder{}, &DefaultWrapper{}, tt.cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%+v: got %v, want %q", tt.cfg, err, tt.err)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		cron     string
+		timezone string
+		from     string
+		next     string
+	}{
+		{"*/15 * * * *", "", "2026-03-01T10:07:00Z", "2026-03-01T10:15:00Z"},
+		{"0 9 * * *", "", "2026-03-01T09:00:00Z", "2026-03-02T09:00:00Z"},
+		{"0 9 * * MON-FRI", "", "2026-03-06T10:00:00Z", "2026-03-09T09:00:00Z"},
+		{"@hourly", "", "2026-03-01T10:59:59Z", "2026-03-01T11:00:00Z"},
+		{"0 9 * * *", "Asia/Tokyo", "2026-03-01T01:00:00Z", "2026-03-02T00:00:00Z"},
+		{"30 2 * * *", "America/New_York", "2026-03-01T12:00:00Z", "2026-03-02T07:30:00Z"},
+	}
+	for _, tt := range tests {
+		cr, err := NewCronRegistrar(&recorder{}, &DefaultWrapper{}, &config.ScheduleConfig{Name: "s", Cron: tt.cron, Timezone: tt.timezone, Queue: "q"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		from, _ := time.Parse(time.RFC3339, tt.from)
+		want, _ := time.Parse(time.RFC3339, tt.next)
+		if next := cr.schedule.Next(from.In(cr.location)); !next.Equal(want) {
+			t.Errorf("%s %s from %s: got %s, want %s", tt.cron, tt.timezone, tt.from, next.UTC().Format(time.RFC3339), tt.next)
+		}
+	}
+}
+
+func TestCronFire(t *testing.T) {
+	w := &DefaultWrapper{}
+	tick := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		cfg    *config.ScheduleConfig
+		pubSub bool
+		name   string
+	}{
+		{&config.ScheduleConfig{Name: "nightly", Cron: "@daily", Queue: "cleanup", Payload: `{"days":30}`, Headers: map[string]string{"x-tenant": "acme"}}, false, "cleanup"},
+		{&config.ScheduleConfig{Name: "hourly", Cron: "@hourly", Event: "hour_passed", Payload: `{}`}, true, "hour_passed"},
+	}
+	for _, tt := range tests {
+		m := &recorder{}
+		cr, err := NewCronRegistrar(m, w, tt.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cr.fire(tick)
+		if len(m.sent) != 1 {
+			t.Fatalf("%s: %d messages sent, want 1", tt.cfg.Name, len(m.sent))
+		}
+		s := m.sent[0]
+		if s.service != tt.cfg.Name || s.name != tt.name || s.pubSub != tt.pubSub {
+			t.Errorf("%s: sent %s to %s, pub/sub %v", tt.cfg.Name, s.service, s.name, s.pubSub)
+		}
+		body, headers, err := w.Unwrap(s.message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != tt.cfg.Payload {
+			t.Errorf("%s: the body is %s, want %s", tt.cfg.Name, body, tt.cfg.Payload)
+		}
+		for k, v := range tt.cfg.Headers {
+			if string(headers[k]) != v {
+				t.Errorf("%s: the header %s is %q, want %q", tt.cfg.Name, k, headers[k], v)
+			}
+		}
+	}
+}
+
+func TestCronFireOnce(t *testing.T) {
+	m := &lockingRecorder{locked: map[time.Time]bool{}}
+	cr, err := NewCronRegistrar(m, &DefaultWrapper{}, &config.ScheduleConfig{Name: "s", Cron: "@hourly", Queue: "q"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tick := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	// every replica fires the same tick, the first one that locks it sends the message.
+	cr.fire(tick)
+	cr.fire(tick)
+	cr.fire(tick.Add(time.Hour))
+	if len(m.sent) != 2 {
+		t.Errorf("%d messages sent, want one per tick", len(m.sent))
+	}
+}
@@ -0,0 +1,67 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DeadLetterQueueNameSuffix constant.
+const DeadLetterQueueNameSuffix = "dead"
+
+// ErrDeadLetterNotFound is returned when the dead letter to replay does not exist.
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter is a message that used up its attempts, kept with the reason of the last failure.
+type DeadLetter struct {
+	ID      string
+	Service string
+	Name    string
+	// Queue is the consumer queue. The queue name for workers, event.service for subscribers.
+	Queue      string
+	Body       []byte
+	Attempt    int
+	StatusCode int
+	Error      string
+	QueuedAt   time.Time
+	FailedAt   time.Time
+}
+
+// DeadLetterQueue is the dead letter queue of a consumer queue.
+type DeadLetterQueue struct {
+	Queue string
+	Count int64
+}
+
+// DeadLetterer is implemented by the messagers that can inspect, replay and purge their dead letters.
+type DeadLetterer interface {
+	DeadLetterQueues() ([]*DeadLetterQueue, error)
+	PeekDeadLetters(queue string, limit int) ([]*DeadLetter, error)
+	// ReplayDeadLetter sends the message back to its consumer queue, starting from the first attempt.
+	// A nil message replays the original one.
+	ReplayDeadLetter(queue string, id string, message []byte) error
+	PurgeDeadLetters(queue string) (int64, error)
+}
+
+// NewDeadLetter creates the dead letter of the delivery that failed for the last time.
+func NewDeadLetter(service string, name string, queue string, d *Delivery, f *Failure) *DeadLetter {
+	l := &DeadLetter{
+		Service:  service,
+		Name:     name,
+		Queue:    queue,
+		Body:     d.Body,
+		Attempt:  d.Attempt,
+		QueuedAt: d.QueuedAt,
+		FailedAt: time.Now(),
+	}
+	if f != nil {
+		l.StatusCode = f.StatusCode
+		l.Error = f.Error
+	}
+	return l
+}
+
+// GetDeadLetterQueueName returns the dead letter queue of the consumer queue. e.g. order_created.billing.dead
+func GetDeadLetterQueueName(queue string) string {
+	return fmt.Sprintf("%s.%s", queue, DeadLetterQueueNameSuffix)
+}
@@ -0,0 +1,76 @@
+package embedded
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+// consume starts a claimer per concurrency, every claimer handles one message at a time.
+func (e *Embedded) consume(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions, pubSub bool) error {
+	db, err := e.connection()
+	if err != nil {
+		return err
+	}
+	queueName := e.getQueueName(service, name, pubSub)
+	if err := e.bind(db, name, queueName); err != nil {
+		return fmt.Errorf("failed to bind queue. %v", err)
+	}
+	q := e.queue(queueName)
+
+	for i := 0; i < opts.Concurrency; i++ {
+		go func() {
+			for {
+				key, r, err := e.claim(db, q)
+				if err != nil {
+					e.exporter.IncError(service)
+					log.Error().Str("service", service).Str("name", name).Msgf("failed to claim a message. %v", err)
+					time.Sleep(WaitToReconnectDuration)
+					continue
+				}
+				if r == nil {
+					select {
+					case <-q.notify:
+					case <-time.After(PollInterval):
+					}
+					continue
+				}
+				// another local consumer may be waiting for the rest of the messages.
+				q.wake()
+				start := time.Now()
+				d := &messaging.Delivery{Body: r.Body, Attempt: r.Attempt, QueuedAt: r.CreatedAt}
+				result := e.invokeConsumerFunc(d, callback)
+				var dead *messaging.DeadLetter
+				switch result.Action {
+				case messaging.Retry:
+					e.exporter.IncConsumeError(service, name)
+				case messaging.Dead:
+					dead = messaging.NewDeadLetter(service, name, queueName, d, result.Failure)
+					e.exporter.IncConsumeError(service, name)
+				}
+				if err := e.ack(db, q, key, r, result, dead); err != nil {
+					log.Error().Msgf("failed to send ACK. %v", err)
+				}
+				e.exporter.ConsumeSeconds(time.Since(start), service, name)
+			}
+		}()
+	}
+
+	return nil
+}
+
+func (e *Embedded) invokeConsumerFunc(d *messaging.Delivery, callback messaging.Callback) (result messaging.Result) {
+	defer func() {
+		if rc := recover(); rc != nil {
+			log.Error().Msgf("error when invoking the consumer function: %+v", rc)
+			result = messaging.Result{Action: messaging.Retry, Delay: RetryDelay}
+		}
+	}()
+	if callback == nil {
+		log.Warn().Msgf("callback function is nil. Make sure you have correct setup for your consumer")
+		return messaging.Result{Action: messaging.Retry, Delay: RetryDelay}
+	}
+	return callback(d)
+}
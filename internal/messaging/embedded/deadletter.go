@@ -0,0 +1,149 @@
+package embedded
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/turgayozgur/messageman/internal/messaging"
+	bolt "go.etcd.io/bbolt"
+)
+
+func putDeadLetter(dead *bolt.Bucket, l *messaging.DeadLetter) error {
+	seq, err := dead.NextSequence()
+	if err != nil {
+		return err
+	}
+	l.ID = strconv.FormatUint(seq, 10)
+	value, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return dead.Put(itob(seq), value)
+}
+
+// DeadLetterQueues .
+func (e *Embedded) DeadLetterQueues() ([]*messaging.DeadLetterQueue, error) {
+	db, err := e.connection()
+	if err != nil {
+		return nil, err
+	}
+	var queues []*messaging.DeadLetterQueue
+	err = db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(queuesBucket).ForEach(func(queueName, _ []byte) error {
+			dead := tx.Bucket(queuesBucket).Bucket(queueName).Bucket(deadBucket)
+			if dead == nil {
+				return nil
+			}
+			if n := dead.Stats().KeyN; n > 0 {
+				queues = append(queues, &messaging.DeadLetterQueue{Queue: string(queueName), Count: int64(n)})
+			}
+			return nil
+		})
+	})
+	return queues, err
+}
+
+// PeekDeadLetters returns the oldest dead letters of the queue without removing them.
+func (e *Embedded) PeekDeadLetters(queue string, limit int) ([]*messaging.DeadLetter, error) {
+	db, err := e.connection()
+	if err != nil {
+		return nil, err
+	}
+	var letters []*messaging.DeadLetter
+	err = db.View(func(tx *bolt.Tx) error {
+		dead := e.deadBucket(tx, queue)
+		if dead == nil {
+			return nil
+		}
+		c := dead.Cursor()
+		for k, v := c.First(); k != nil && (limit <= 0 || len(letters) < limit); k, v = c.Next() {
+			l := &messaging.DeadLetter{}
+			if err := json.Unmarshal(v, l); err != nil {
+				return err
+			}
+			letters = append(letters, l)
+		}
+		return nil
+	})
+	return letters, err
+}
+
+// ReplayDeadLetter moves the dead letter to the ready bucket of its queue in one transaction.
+func (e *Embedded) ReplayDeadLetter(queue string, id string, message []byte) error {
+	seq, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return messaging.ErrDeadLetterNotFound
+	}
+	db, err := e.connection()
+	if err != nil {
+		return err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		dead := e.deadBucket(tx, queue)
+		if dead == nil {
+			return messaging.ErrDeadLetterNotFound
+		}
+		v := dead.Get(itob(seq))
+		if v == nil {
+			return messaging.ErrDeadLetterNotFound
+		}
+		l := &messaging.DeadLetter{}
+		if err := json.Unmarshal(v, l); err != nil {
+			return err
+		}
+		if message == nil {
+			message = l.Body
+		}
+		value, err := json.Marshal(&record{Body: message, Attempt: 1, CreatedAt: time.Now()})
+		if err != nil {
+			return err
+		}
+		ready := tx.Bucket(queuesBucket).Bucket([]byte(queue)).Bucket(readyBucket)
+		next, err := ready.NextSequence()
+		if err != nil {
+			return err
+		}
+		if err := ready.Put(itob(next), value); err != nil {
+			return err
+		}
+		return dead.Delete(itob(seq))
+	})
+	if err != nil {
+		return err
+	}
+	e.queue(queue).wake()
+	return nil
+}
+
+// PurgeDeadLetters .
+func (e *Embedded) PurgeDeadLetters(queue string) (int64, error) {
+	db, err := e.connection()
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	err = db.Update(func(tx *bolt.Tx) error {
+		dead := e.deadBucket(tx, queue)
+		if dead == nil {
+			return nil
+		}
+		c := dead.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+func (e *Embedded) deadBucket(tx *bolt.Tx, queue string) *bolt.Bucket {
+	q := tx.Bucket(queuesBucket).Bucket([]byte(queue))
+	if q == nil {
+		return nil
+	}
+	return q.Bucket(deadBucket)
+}
@@ -0,0 +1,172 @@
+package embedded
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/messaging"
+	"github.com/turgayozgur/messageman/internal/metrics"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// RetryDelay constant. Matches the TTL of the RabbitMQ retry queue. Used when the callback fails unexpectedly.
+	RetryDelay = 30 * time.Second
+	// PollInterval constant. How often an idle consumer looks for the due retries.
+	PollInterval = time.Second
+	// OpenTimeout constant. Another process holding the file makes open fail after this.
+	OpenTimeout = 5 * time.Second
+	// WaitToReconnectDuration constant.
+	WaitToReconnectDuration = 5 * time.Second
+	// ConnectionName constant.
+	ConnectionName = "embedded"
+)
+
+// Embedded messager keeps queues, events and retries in a bbolt file. Messages survive
+// restarts, but the file can only be opened by one messageman process.
+type Embedded struct {
+	exporter metrics.Exporter
+	recover  chan string
+	mu       sync.Mutex
+	db       *bolt.DB
+	queues   map[string]*queue
+}
+
+// New ctor
+func New(exporter metrics.Exporter) *Embedded {
+	return &Embedded{
+		exporter: exporter,
+		queues:   make(map[string]*queue),
+	}
+}
+
+// EnsureCanConnect to ensure the database file can be opened
+func (e *Embedded) EnsureCanConnect() bool {
+	if _, err := e.connection(); err != nil {
+		log.Error().Msgf("failed to open the embedded broker. path:%s error:%v", config.Cfg.Embedded.Path, err)
+		return false
+	}
+	log.Info().Msg("successfully opened the embedded broker")
+	return true
+}
+
+// NotifyRecover .
+func (e *Embedded) NotifyRecover(ch chan string) chan string {
+	e.recover = ch
+	return e.recover
+}
+
+// Queue .
+func (e *Embedded) Queue(service string, name string, message []byte) error {
+	return e.QueueAt(service, name, message, time.Time{})
+}
+
+// QueueAt .
+func (e *Embedded) QueueAt(service string, name string, message []byte, at time.Time) (err error) {
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			log.Error().Err(err).Msg("could not be queued")
+			e.exporter.IncSendError(service, name)
+		}
+		e.exporter.SendSeconds(time.Since(start), service, name)
+	}()
+	if err := e.send(name, message, at); err != nil {
+		return err
+	}
+	log.Debug().Str("name", name).Msgf("queued")
+	return nil
+}
+
+// Work .
+func (e *Embedded) Work(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) error {
+	if err := e.consume(service, name, callback, opts, false); err != nil {
+		return err
+	}
+	e.exporter.IncConsumer(service, name)
+	return nil
+}
+
+// Publish .
+func (e *Embedded) Publish(service string, name string, message []byte) error {
+	return e.PublishAt(service, name, message, time.Time{})
+}
+
+// PublishAt .
+func (e *Embedded) PublishAt(service string, name string, message []byte, at time.Time) (err error) {
+	start := time.Now()
+	defer func() {
+		if err == messaging.ErrUnroutable {
+			log.Warn().Str("name", name).Msg("published to no subscribers")
+		} else if err != nil {
+			log.Error().Err(err).Msg("could not be published")
+			e.exporter.IncSendError(service, name)
+		}
+		e.exporter.SendSeconds(time.Since(start), service, name)
+	}()
+	if err := e.send(name, message, at); err != nil {
+		return err
+	}
+	log.Debug().Str("name", name).Msgf("published")
+	return nil
+}
+
+// Subscribe .
+func (e *Embedded) Subscribe(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) error {
+	if err := e.consume(service, name, callback, opts, true); err != nil {
+		return err
+	}
+	e.exporter.IncConsumer(service, name)
+	return nil
+}
+
+func (e *Embedded) connection() (*bolt.DB, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.db != nil {
+		return e.db, nil
+	}
+	db, err := bolt.Open(config.Cfg.Embedded.Path, 0600, &bolt.Options{Timeout: OpenTimeout})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(bindingsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(queuesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	e.db = db
+	e.exporter.IncConnection(ConnectionName)
+	return e.db, nil
+}
+
+// queue returns the in process state of the queue shared by its local consumers.
+func (e *Embedded) queue(queueName string) *queue {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	q, ok := e.queues[queueName]
+	if !ok {
+		q = newQueue(queueName)
+		e.queues[queueName] = q
+	}
+	return q
+}
+
+func (e *Embedded) getQueueName(service string, name string, pubSub bool) string {
+	var queueName string
+	if pubSub {
+		queueName = fmt.Sprintf("%s.%s", name, service)
+	} else {
+		queueName = name
+	}
+	return queueName
+}
@@ -0,0 +1,214 @@
+package embedded
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/turgayozgur/messageman/internal/messaging"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// bindingsBucket keeps a nested bucket per queue or event name, keyed by the bound queue names.
+	bindingsBucket = []byte("bindings")
+	// queuesBucket keeps a nested bucket per queue with the ready, the delayed and the dead buckets.
+	queuesBucket = []byte("queues")
+	// readyBucket is keyed by the sequence, so the oldest message comes first.
+	readyBucket = []byte("ready")
+	// delayedBucket is keyed by the due time and the sequence, so the first due message comes first.
+	delayedBucket = []byte("delayed")
+	// deadBucket is keyed by the sequence and keeps the dead letters of the queue.
+	deadBucket = []byte("dead")
+)
+
+// record is a stored delivery.
+type record struct {
+	Body      []byte
+	Attempt   int
+	CreatedAt time.Time
+}
+
+// queue is the in process state of a stored queue. Inflight keeps the local consumers from claiming the same message.
+type queue struct {
+	name     string
+	notify   chan struct{}
+	mu       sync.Mutex
+	inflight map[uint64]bool
+}
+
+func newQueue(name string) *queue {
+	return &queue{
+		name:     name,
+		notify:   make(chan struct{}, 1),
+		inflight: make(map[uint64]bool),
+	}
+}
+
+func (q *queue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// send stores the message on every queue bound to the name in one transaction. Like a RabbitMQ
+// exchange without bindings, the message is unroutable if no worker or subscriber registered yet.
+// A message due later goes to the delayed bucket like a retry.
+func (e *Embedded) send(name string, message []byte, at time.Time) error {
+	db, err := e.connection()
+	if err != nil {
+		return err
+	}
+	var bound []string
+	err = db.Update(func(tx *bolt.Tx) error {
+		bindings := tx.Bucket(bindingsBucket).Bucket([]byte(name))
+		if bindings == nil {
+			return nil
+		}
+		value, err := json.Marshal(&record{Body: message, Attempt: 1, CreatedAt: time.Now()})
+		if err != nil {
+			return err
+		}
+		return bindings.ForEach(func(queueName, _ []byte) error {
+			qb := tx.Bucket(queuesBucket).Bucket(queueName)
+			bound = append(bound, string(queueName))
+			if at.After(time.Now()) {
+				return putDelayed(qb.Bucket(delayedBucket), value, at)
+			}
+			ready := qb.Bucket(readyBucket)
+			seq, err := ready.NextSequence()
+			if err != nil {
+				return err
+			}
+			return ready.Put(itob(seq), value)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish a message. %v", err)
+	}
+	if len(bound) == 0 {
+		return messaging.ErrUnroutable
+	}
+	for _, queueName := range bound {
+		e.queue(queueName).wake()
+	}
+	return nil
+}
+
+// bind declares the queue buckets and binds the queue to the name.
+func (e *Embedded) bind(db *bolt.DB, name string, queueName string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bindings, err := tx.Bucket(bindingsBucket).CreateBucketIfNotExists([]byte(name))
+		if err != nil {
+			return err
+		}
+		if err := bindings.Put([]byte(queueName), []byte{}); err != nil {
+			return err
+		}
+		q, err := tx.Bucket(queuesBucket).CreateBucketIfNotExists([]byte(queueName))
+		if err != nil {
+			return err
+		}
+		if _, err := q.CreateBucketIfNotExists(readyBucket); err != nil {
+			return err
+		}
+		if _, err := q.CreateBucketIfNotExists(delayedBucket); err != nil {
+			return err
+		}
+		_, err = q.CreateBucketIfNotExists(deadBucket)
+		return err
+	})
+}
+
+// claim moves the due delayed messages to the ready bucket and returns the oldest ready message that is not in flight.
+func (e *Embedded) claim(db *bolt.DB, q *queue) (uint64, *record, error) {
+	now := time.Now()
+	var key uint64
+	var r *record
+	err := db.Update(func(tx *bolt.Tx) error {
+		qb := tx.Bucket(queuesBucket).Bucket([]byte(q.name))
+		ready := qb.Bucket(readyBucket)
+		delayed := qb.Bucket(delayedBucket)
+
+		c := delayed.Cursor()
+		for k, v := c.First(); k != nil && int64(binary.BigEndian.Uint64(k[:8])) <= now.UnixNano(); k, v = c.First() {
+			seq, err := ready.NextSequence()
+			if err != nil {
+				return err
+			}
+			if err := ready.Put(itob(seq), v); err != nil {
+				return err
+			}
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		c = ready.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			seq := binary.BigEndian.Uint64(k)
+			if q.inflight[seq] {
+				continue
+			}
+			r = &record{}
+			if err := json.Unmarshal(v, r); err != nil {
+				return err
+			}
+			key = seq
+			q.inflight[seq] = true
+			return nil
+		}
+		return nil
+	})
+	return key, r, err
+}
+
+// ack removes the message. Retry moves it to the delayed bucket until the due time, Dead moves the dead letter to the dead bucket.
+func (e *Embedded) ack(db *bolt.DB, q *queue, key uint64, r *record, result messaging.Result, dead *messaging.DeadLetter) error {
+	defer func() {
+		q.mu.Lock()
+		delete(q.inflight, key)
+		q.mu.Unlock()
+	}()
+	return db.Update(func(tx *bolt.Tx) error {
+		qb := tx.Bucket(queuesBucket).Bucket([]byte(q.name))
+		if err := qb.Bucket(readyBucket).Delete(itob(key)); err != nil {
+			return err
+		}
+		if result.Action == messaging.Dead {
+			return putDeadLetter(qb.Bucket(deadBucket), dead)
+		}
+		if result.Action != messaging.Retry {
+			return nil
+		}
+		r.Attempt++
+		value, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		return putDelayed(qb.Bucket(delayedBucket), value, time.Now().Add(result.Delay))
+	})
+}
+
+// putDelayed keys the message by the due time, so the first due message comes first.
+func putDelayed(delayed *bolt.Bucket, value []byte, at time.Time) error {
+	seq, err := delayed.NextSequence()
+	if err != nil {
+		return err
+	}
+	dueKey := make([]byte, 16)
+	binary.BigEndian.PutUint64(dueKey[:8], uint64(at.UnixNano()))
+	binary.BigEndian.PutUint64(dueKey[8:], seq)
+	return delayed.Put(dueKey, value)
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
@@ -0,0 +1,109 @@
+package messaging
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/config"
+	"google.golang.org/grpc/codes"
+)
+
+// FailurePolicy decides whether a failure is worth another attempt and what happens to the message if not.
+type FailurePolicy struct {
+	// Drop acknowledges the messages that failed for good instead of dead lettering them.
+	Drop        bool
+	statusCodes map[int]bool
+	grpcCodes   map[codes.Code]bool
+}
+
+// NewFailurePolicy creates the policy from the failure settings of a service. Unset values fall back to
+// the defaults, client errors are not retried.
+func NewFailurePolicy(cfg *config.FailureConfig) *FailurePolicy {
+	p := &FailurePolicy{
+		grpcCodes: map[codes.Code]bool{
+			codes.Unknown:           true,
+			codes.DeadlineExceeded:  true,
+			codes.ResourceExhausted: true,
+			codes.Aborted:           true,
+			codes.Internal:          true,
+			codes.Unavailable:       true,
+		},
+	}
+	if cfg == nil {
+		return p
+	}
+	p.Drop = cfg.Action == "drop"
+	if cfg.RetryableStatusCodes != nil {
+		p.statusCodes = make(map[int]bool, len(cfg.RetryableStatusCodes))
+		for _, c := range cfg.RetryableStatusCodes {
+			p.statusCodes[c] = true
+		}
+	}
+	if cfg.RetryableGRPCCodes != nil {
+		p.grpcCodes = make(map[codes.Code]bool, len(cfg.RetryableGRPCCodes))
+		for _, name := range cfg.RetryableGRPCCodes {
+			c, ok := grpcCodes[name]
+			if !ok {
+				log.Warn().Msgf("unknown gRPC code %s on the retryable_grpc_codes", name)
+				continue
+			}
+			p.grpcCodes[c] = true
+		}
+	}
+	return p
+}
+
+// Retryable returns false if the failure will not go away by delivering the same message again.
+// Failures without a response, like timeouts and refused connections, are always retried.
+func (p *FailurePolicy) Retryable(f *Failure) bool {
+	if f.GRPC {
+		return p.grpcCodes[codes.Code(f.StatusCode)]
+	}
+	if f.StatusCode == 0 {
+		return true
+	}
+	if p.statusCodes != nil {
+		return p.statusCodes[f.StatusCode]
+	}
+	switch f.StatusCode {
+	case 408, 425, 429:
+		return true
+	}
+	return f.StatusCode >= 500
+}
+
+// grpcCodes are the gRPC codes by their names on the configuration file. e.g. InvalidArgument
+var grpcCodes = func() map[string]codes.Code {
+	m := make(map[string]codes.Code)
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		m[c.String()] = c
+	}
+	return m
+}()
+
+// classify tells the messager what to do with the delivery that failed with f. The outcome is logged on l, for the
+// kind of the message, "job" or "message".
+func classify(failure *FailurePolicy, retry *RetryPolicy, d *Delivery, f *Failure, l zerolog.Logger, kind string) Result {
+	if !failure.Retryable(f) {
+		if failure.Drop {
+			l.Error().Msgf("%s dropped. Non retryable failure on attempt %d", kind, d.Attempt)
+			return Result{Action: Ack, Failure: f}
+		}
+		l.Error().Msgf("%s dead lettered. Non retryable failure on attempt %d", kind, d.Attempt)
+		return Result{Action: Dead, Failure: f}
+	}
+	delay, ok := retry.Next(d.Attempt)
+	if !ok {
+		l.Error().Msgf("%s dead lettered. No attempts left after %d attempts", kind, d.Attempt)
+		return Result{Action: Dead, Failure: f}
+	}
+	if f.RetryAfter > 0 {
+		delay = f.RetryAfter
+	}
+	if !d.RetryBy.IsZero() && time.Now().Add(delay).After(d.RetryBy) {
+		l.Error().Msgf("%s dead lettered. Retried in place for too long after %d attempts", kind, d.Attempt)
+		return Result{Action: Dead, Failure: f}
+	}
+	return Result{Action: Retry, Delay: delay, Failure: f}
+}
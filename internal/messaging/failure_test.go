@@ -0,0 +1,105 @@
+package messaging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/turgayozgur/messageman/config"
+	"google.golang.org/grpc/codes"
+)
+
+func TestFailurePolicyDefaults(t *testing.T) {
+	p := NewFailurePolicy(nil)
+	tests := []struct {
+		failure   *Failure
+		retryable bool
+	}{
+		{&Failure{Error: "connection refused"}, true},
+		{&Failure{StatusCode: 408}, true},
+		{&Failure{StatusCode: 425}, true},
+		{&Failure{StatusCode: 429}, true},
+		{&Failure{StatusCode: 500}, true},
+		{&Failure{StatusCode: 503}, true},
+		{&Failure{StatusCode: 400}, false},
+		{&Failure{StatusCode: 404}, false},
+		{&Failure{StatusCode: 422}, false},
+		{&Failure{StatusCode: 302}, false},
+		{&Failure{StatusCode: int(codes.Unavailable), GRPC: true}, true},
+		{&Failure{StatusCode: int(codes.DeadlineExceeded), GRPC: true}, true},
+		{&Failure{StatusCode: int(codes.Unknown), GRPC: true}, true},
+		{&Failure{StatusCode: int(codes.InvalidArgument), GRPC: true}, false},
+		{&Failure{StatusCode: int(codes.NotFound), GRPC: true}, false},
+		{&Failure{StatusCode: int(codes.Unimplemented), GRPC: true}, false},
+	}
+	for _, tt := range tests {
+		if got := p.Retryable(tt.failure); got != tt.retryable {
+			t.Errorf("%+v: retryable %v, want %v", tt.failure, got, tt.retryable)
+		}
+	}
+	if p.Drop {
+		t.Error("the failures are dead lettered by default")
+	}
+}
+
+func TestFailurePolicyConfigured(t *testing.T) {
+	p := NewFailurePolicy(&config.FailureConfig{
+		Action:               "drop",
+		RetryableStatusCodes: []int{409},
+		RetryableGRPCCodes:   []string{"NotFound", "NoSuchCode"},
+	})
+	tests := []struct {
+		failure   *Failure
+		retryable bool
+	}{
+		{&Failure{StatusCode: 409}, true},
+		{&Failure{StatusCode: 503}, false},
+		{&Failure{StatusCode: 429}, false},
+		// a failure without a response is always retried.
+		{&Failure{Error: "timeout"}, true},
+		{&Failure{StatusCode: int(codes.NotFound), GRPC: true}, true},
+		{&Failure{StatusCode: int(codes.Unavailable), GRPC: true}, false},
+	}
+	for _, tt := range tests {
+		if got := p.Retryable(tt.failure); got != tt.retryable {
+			t.Errorf("%+v: retryable %v, want %v", tt.failure, got, tt.retryable)
+		}
+	}
+	if !p.Drop {
+		t.Error("expected the failures to be dropped")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	retry := NewRetryPolicy(&config.RetryConfig{MaxAttempts: 3, InitialDelayMs: 100, Multiplier: 2})
+	dead := NewFailurePolicy(nil)
+	drop := NewFailurePolicy(&config.FailureConfig{Action: "drop"})
+	now := time.Now()
+	tests := []struct {
+		name    string
+		failure *FailurePolicy
+		d       *Delivery
+		f       *Failure
+		action  Action
+		delay   time.Duration
+	}{
+		{"retried", dead, &Delivery{Attempt: 1}, &Failure{StatusCode: 503}, Retry, 100 * time.Millisecond},
+		{"backed off", dead, &Delivery{Attempt: 2}, &Failure{StatusCode: 503}, Retry, 200 * time.Millisecond},
+		{"retried after", dead, &Delivery{Attempt: 1}, &Failure{StatusCode: 429, RetryAfter: 5 * time.Second}, Retry, 5 * time.Second},
+		{"no attempts left", dead, &Delivery{Attempt: 3}, &Failure{StatusCode: 503}, Dead, 0},
+		{"not retryable", dead, &Delivery{Attempt: 1}, &Failure{StatusCode: 400}, Dead, 0},
+		{"dropped", drop, &Delivery{Attempt: 1}, &Failure{StatusCode: 400}, Ack, 0},
+		{"dropped after the attempts", drop, &Delivery{Attempt: 3}, &Failure{StatusCode: 503}, Dead, 0},
+		{"retried in place", dead, &Delivery{Attempt: 1, RetryBy: now.Add(time.Minute)}, &Failure{StatusCode: 503}, Retry, 100 * time.Millisecond},
+		{"retried in place for too long", dead, &Delivery{Attempt: 1, RetryBy: now.Add(time.Second)}, &Failure{StatusCode: 503, RetryAfter: time.Minute}, Dead, 0},
+	}
+	for _, tt := range tests {
+		r := classify(tt.failure, retry, tt.d, tt.f, zerolog.Nop(), "job")
+		if r.Action != tt.action || r.Delay != tt.delay {
+			t.Errorf("%s: got %v %v, want %v %v", tt.name, r.Action, r.Delay, tt.action, tt.delay)
+		}
+		if r.Failure != tt.f {
+			t.Errorf("%s: the failure is not on the result", tt.name)
+		}
+	}
+}
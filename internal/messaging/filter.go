@@ -0,0 +1,426 @@
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Filter is a boolean expression over the proxied headers and the JSON body of a message, like
+// headers.tenant == "acme" && body.total > 100
+//
+// The headers.<name> and body.<path> fields are compared with ==, !=, >, >=, < and <= to strings, numbers, true,
+// false and null, and combined with &&, || and !. A missing field is null. A field on its own is true unless it is
+// missing, false, zero or empty. Header values are strings, compared as numbers to a number.
+type Filter struct {
+	root     node
+	needBody bool
+}
+
+// NewFilter parses the expression.
+func NewFilter(expression string) (*Filter, error) {
+	p := &parser{input: expression}
+	p.next()
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.token.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", p.token.text, p.token.pos)
+	}
+	return &Filter{root: root, needBody: p.needBody}, nil
+}
+
+// Match returns true if the message passes the filter. A body that is not JSON has no fields.
+func (f *Filter) Match(body []byte, headers map[string][]byte) bool {
+	s := &scope{headers: headers}
+	if f.needBody {
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err == nil {
+			s.body = v
+		}
+	}
+	return truthy(f.root.eval(s))
+}
+
+type scope struct {
+	headers map[string][]byte
+	body    interface{}
+}
+
+type node interface {
+	eval(s *scope) interface{}
+}
+
+type literal struct{ value interface{} }
+
+func (n *literal) eval(s *scope) interface{} { return n.value }
+
+type field struct {
+	header string
+	path   []string
+}
+
+func (n *field) eval(s *scope) interface{} {
+	if n.path == nil {
+		if v, ok := header(s.headers, n.header); ok {
+			return string(v)
+		}
+		return nil
+	}
+	v := s.body
+	for _, name := range n.path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[name]
+	}
+	return v
+}
+
+type not struct{ operand node }
+
+func (n *not) eval(s *scope) interface{} { return !truthy(n.operand.eval(s)) }
+
+type logical struct {
+	and         bool
+	left, right node
+}
+
+func (n *logical) eval(s *scope) interface{} {
+	if n.and {
+		return truthy(n.left.eval(s)) && truthy(n.right.eval(s))
+	}
+	return truthy(n.left.eval(s)) || truthy(n.right.eval(s))
+}
+
+type comparison struct {
+	op          string
+	left, right node
+}
+
+func (n *comparison) eval(s *scope) interface{} {
+	l, r := n.left.eval(s), n.right.eval(s)
+	switch n.op {
+	case "==":
+		return equal(l, r)
+	case "!=":
+		return !equal(l, r)
+	}
+	c, ok := compare(l, r)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	}
+	return c <= 0
+}
+
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return true
+}
+
+// numbers returns the values as numbers if one is a number and the other is a number or a numeric string.
+func numbers(l, r interface{}) (float64, float64, bool) {
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok && !rok {
+		return 0, 0, false
+	}
+	if !lok {
+		s, ok := l.(string)
+		if !ok {
+			return 0, 0, false
+		}
+		var err error
+		if lf, err = strconv.ParseFloat(s, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if !rok {
+		s, ok := r.(string)
+		if !ok {
+			return 0, 0, false
+		}
+		var err error
+		if rf, err = strconv.ParseFloat(s, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return lf, rf, true
+}
+
+func equal(l, r interface{}) bool {
+	if lf, rf, ok := numbers(l, r); ok {
+		return lf == rf
+	}
+	switch l := l.(type) {
+	case nil:
+		return r == nil
+	case bool:
+		b, ok := r.(bool)
+		return ok && l == b
+	case string:
+		s, ok := r.(string)
+		return ok && l == s
+	}
+	return false
+}
+
+func compare(l, r interface{}) (int, bool) {
+	if lf, rf, ok := numbers(l, r); ok {
+		switch {
+		case lf < rf:
+			return -1, true
+		case lf > rf:
+			return 1, true
+		}
+		return 0, true
+	}
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	if lok && rok {
+		return strings.Compare(ls, rs), true
+	}
+	return 0, false
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type parser struct {
+	input    string
+	pos      int
+	token    token
+	err      error
+	needBody bool
+}
+
+// next reads the next token. Identifiers may have dashes, like the header names.
+func (p *parser) next() {
+	for p.pos < len(p.input) {
+		r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		p.pos += size
+	}
+	start := p.pos
+	if p.pos >= len(p.input) {
+		p.token = token{kind: tokenEOF, text: "end", pos: start}
+		return
+	}
+	c, size := utf8.DecodeRuneInString(p.input[p.pos:])
+	switch {
+	case c == '"' || c == '\'':
+		p.pos++
+		var b strings.Builder
+		for p.pos < len(p.input) {
+			r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+			if r == c {
+				break
+			}
+			if r == '\\' && p.pos+size < len(p.input) {
+				p.pos += size
+				r, size = utf8.DecodeRuneInString(p.input[p.pos:])
+			}
+			b.WriteString(p.input[p.pos : p.pos+size])
+			p.pos += size
+		}
+		if p.pos >= len(p.input) {
+			p.err = fmt.Errorf("unterminated string at %d", start)
+			p.token = token{kind: tokenEOF, text: "end", pos: start}
+			return
+		}
+		p.pos++
+		p.token = token{kind: tokenString, text: b.String(), pos: start}
+	case isDigit(c) || c == '-' && p.pos+1 < len(p.input) && isDigit(rune(p.input[p.pos+1])):
+		p.pos++
+		for p.pos < len(p.input) && (isDigit(rune(p.input[p.pos])) || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		p.token = token{kind: tokenNumber, text: p.input[start:p.pos], pos: start}
+	case c == '_' || unicode.IsLetter(c):
+		for p.pos < len(p.input) {
+			r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+			if r != '_' && r != '-' && r != '.' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				break
+			}
+			p.pos += size
+		}
+		p.token = token{kind: tokenIdent, text: p.input[start:p.pos], pos: start}
+	default:
+		for _, op := range []string{"&&", "||", "==", "!=", ">=", "<=", ">", "<", "!", "(", ")"} {
+			if strings.HasPrefix(p.input[p.pos:], op) {
+				p.pos += len(op)
+				p.token = token{kind: tokenOp, text: op, pos: start}
+				return
+			}
+		}
+		p.err = fmt.Errorf("unexpected %q at %d", p.input[p.pos:p.pos+size], start)
+		p.token = token{kind: tokenEOF, text: "end", pos: start}
+	}
+}
+
+// isDigit matches the ASCII digits only, the numbers are parsed by strconv.
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.token.kind == tokenOp && p.token.text == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.token.kind == tokenOp && p.token.text == "&&" {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.token.kind == tokenOp && p.token.text == "!" {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &not{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.token.kind == tokenOp {
+		switch op := p.token.text; op {
+		case "==", "!=", ">", ">=", "<", "<=":
+			p.next()
+			right, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return &comparison{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	t := p.token
+	switch t.kind {
+	case tokenString:
+		p.next()
+		return &literal{value: t.text}, nil
+	case tokenNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		p.next()
+		return &literal{value: v}, nil
+	case tokenIdent:
+		p.next()
+		switch t.text {
+		case "true":
+			return &literal{value: true}, nil
+		case "false":
+			return &literal{value: false}, nil
+		case "null":
+			return &literal{value: nil}, nil
+		}
+		if f, ok := parseField(t.text); ok {
+			p.needBody = p.needBody || f.path != nil
+			return f, nil
+		}
+		return nil, fmt.Errorf("unknown field %q at %d, expected headers.<name> or body.<path>", t.text, t.pos)
+	case tokenOp:
+		if t.text == "(" {
+			p.next()
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if p.token.kind != tokenOp || p.token.text != ")" {
+				return nil, fmt.Errorf("expected ) at %d", p.token.pos)
+			}
+			p.next()
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+// header returns the value of the header, the names are case insensitive like the HTTP headers. The proxied headers
+// that are not on the request are kept as null, they are missing.
+func header(headers map[string][]byte, name string) ([]byte, bool) {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) && v != nil {
+			return v, true
+		}
+	}
+	return nil, false
+}
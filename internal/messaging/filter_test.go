@@ -0,0 +1,132 @@
+package messaging
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterMatch(t *testing.T) {
+	headers := map[string][]byte{
+		"X-Tenant": []byte("acme"),
+		"x-count":  []byte("10"),
+		"x-empty":  []byte(""),
+		"x-absent": nil,
+	}
+	body := []byte(`{"total":150,"code":"10","name":"çağrı","paid":false,"items":[],"customer":{"tier":"gold","score":0}}`)
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		// fields and literals
+		{`headers.x-tenant == "acme"`, true},
+		{`headers.X-TENANT == 'acme'`, true},
+		{`body.customer.tier == "gold"`, true},
+		{`body.total > 100`, true},
+		{`body.total >= 150 && body.total <= 150`, true},
+		{`body.total < 100`, false},
+		{`body.total != 150`, false},
+		{`body.paid == false`, true},
+		{`body.name == "çağrı"`, true},
+		{`body.name == "cagri"`, false},
+		// precedence, && binds tighter than ||
+		{`true || false && false`, true},
+		{`(true || false) && false`, false},
+		{`false && false || true`, true},
+		{`false && (false || true)`, false},
+		// negation
+		{`!body.paid`, true},
+		{`!!body.paid`, false},
+		{`!body.total > 100`, false},
+		{`!(body.total < 100)`, true},
+		{`!headers.x-missing`, true},
+		// numbers against numeric strings
+		{`headers.x-count == 10`, true},
+		{`headers.x-count > 9`, true},
+		{`body.code == 10`, true},
+		{`body.code == 10.0`, true},
+		{`body.code == "10"`, true},
+		{`body.code == "10.0"`, false},
+		{`headers.x-tenant == 0`, false},
+		{`headers.x-tenant > 0`, false},
+		// strings compare as strings
+		{`headers.x-count < "9"`, true},
+		{`body.customer.tier > "bronze"`, true},
+		// missing fields are null
+		{`headers.x-missing == null`, true},
+		{`headers.x-absent == null`, true},
+		{`body.missing == null`, true},
+		{`body.customer.missing.deep == null`, true},
+		{`body.total.deep == null`, true},
+		{`body.missing != null`, false},
+		{`body.missing > 0`, false},
+		{`body.missing < 0`, false},
+		{`headers.x-missing`, false},
+		// truthiness of a field on its own
+		{`headers.x-tenant`, true},
+		{`headers.x-empty`, false},
+		{`body.customer.score`, false},
+		{`body.items`, true},
+		{`body.customer`, true},
+	}
+	for _, tt := range tests {
+		f, err := NewFilter(tt.expression)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expression, err)
+			continue
+		}
+		if got := f.Match(body, headers); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.expression, got, tt.want)
+		}
+	}
+}
+
+func TestFilterNotJSON(t *testing.T) {
+	f, err := NewFilter(`body.total == null && headers.x-tenant == "acme"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Match([]byte("not json"), map[string][]byte{"x-tenant": []byte("acme")}) {
+		t.Error("a body that is not JSON has no fields")
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		err        string
+	}{
+		{`headers.x-tenant == "acme`, "unterminated string at 20"},
+		{`body.name == 'çağrı`, "unterminated string at 13"},
+		{`headers.x-tenant == "acme" "beta"`, `unexpected "beta" at 27`},
+		{`body.total > 100 body.paid`, `unexpected "body.paid" at 17`},
+		{`(body.total > 100))`, `unexpected ")" at 18`},
+		{`(body.total > 100`, "expected ) at 17"},
+		{`body.total >`, `unexpected "end" at 12`},
+		{`body.total # 1`, `unexpected "#" at 11`},
+		{`body.total == €`, `unexpected "€" at 14`},
+		{`total > 100`, `unknown field "total" at 0`},
+		{`body.total > 1.2.3`, `invalid number "1.2.3" at 13`},
+		{``, `unexpected "end" at 0`},
+	}
+	for _, tt := range tests {
+		_, err := NewFilter(tt.expression)
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.expression)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got %q, want %q", tt.expression, err, tt.err)
+		}
+	}
+}
+
+func TestFilterUnicodeSpace(t *testing.T) {
+	// a no-break space between the tokens.
+	f, err := NewFilter("body.total > 100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Match([]byte(`{"total":150}`), nil) {
+		t.Error("expected a match")
+	}
+}
@@ -0,0 +1,93 @@
+package jetstream
+
+import (
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/config"
+)
+
+// jetStream returns the JetStream context of the shared connection and makes sure the given stream exists.
+func (j *JetStream) jetStream(stream string) (nats.JetStreamContext, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.conn == nil {
+		if err := j.connect(); err != nil {
+			return nil, err
+		}
+	}
+	if !j.streams[stream] {
+		if err := j.stream(stream); err != nil {
+			return nil, err
+		}
+		j.streams[stream] = true
+	}
+	return j.js, nil
+}
+
+func (j *JetStream) connect() error {
+	conn, err := nats.Connect(
+		config.Cfg.NATS.Url,
+		nats.Name(ConnectionName),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(WaitToReconnectDuration),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			j.exporter.DecConnection(ConnectionName)
+			log.Error().Str("name", ConnectionName).Msgf("connection closed, reason: %v", err)
+		}),
+		nats.ReconnectHandler(func(_ *nats.Conn) {
+			j.exporter.IncConnection(ConnectionName)
+			log.Info().Str("name", ConnectionName).Msg("successfully reconnected")
+		}),
+		nats.ClosedHandler(func(_ *nats.Conn) {
+			log.Info().Str("name", ConnectionName).Msg("connection closed")
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the NATS server. %v", err)
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to create the JetStream context. %v", err)
+	}
+	j.conn = conn
+	j.js = js
+	j.exporter.IncConnection(ConnectionName)
+	return nil
+}
+
+// stream declares the work-queue streams for queues and scheduled messages, the interest stream for events or the
+// limits stream for dead letters.
+func (j *JetStream) stream(name string) error {
+	if _, err := j.js.StreamInfo(name); err == nil {
+		return nil
+	} else if err != nats.ErrStreamNotFound {
+		return fmt.Errorf("failed to get the stream info. %v", err)
+	}
+
+	cfg := &nats.StreamConfig{
+		Name:    name,
+		Storage: nats.FileStorage,
+	}
+	switch name {
+	case EventsStreamName:
+		cfg.Subjects = []string{EventsSubjectPrefix + ".>"}
+		cfg.Retention = nats.InterestPolicy
+	case ScheduledStreamName:
+		cfg.Subjects = []string{ScheduledSubjectPrefix + ".>"}
+		cfg.Retention = nats.WorkQueuePolicy
+	case DeadLettersStreamName:
+		cfg.Subjects = []string{DeadLettersSubjectPrefix + ".>"}
+		cfg.Retention = nats.LimitsPolicy
+	default:
+		cfg.Subjects = []string{QueuesSubjectPrefix + ".>"}
+		cfg.Retention = nats.WorkQueuePolicy
+	}
+	if _, err := j.js.AddStream(cfg); err != nil {
+		return fmt.Errorf("failed to declare a stream. %v", err)
+	}
+	return nil
+}
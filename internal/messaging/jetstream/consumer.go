@@ -0,0 +1,140 @@
+package jetstream
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+// consume starts the fetcher of the durable consumer. The fetcher fetches as many messages as the prefetch count and
+// hands them over to the handlers, the fetcher waits while every handler is busy.
+func (j *JetStream) consume(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions, pubSub bool) error {
+	stream := QueuesStreamName
+	if pubSub {
+		stream = EventsStreamName
+	}
+	js, err := j.jetStream(stream)
+	if err != nil {
+		return err
+	}
+	j.startScheduler()
+
+	consumerName := j.getConsumerName(service, name, pubSub)
+	queueName := name
+	if pubSub {
+		queueName = fmt.Sprintf("%s.%s", name, service)
+	}
+
+	subscription, err := js.PullSubscribe(
+		j.getSubject(name, pubSub),
+		consumerName,
+		nats.BindStream(stream),
+		nats.AckExplicit(),
+		nats.AckWait(AckWait),
+		nats.MaxDeliver(-1),
+	)
+	if err != nil {
+		return err
+	}
+
+	handlers := make(chan struct{}, opts.Concurrency)
+	go func() {
+		for {
+			messages, err := subscription.Fetch(opts.PrefetchCount(), nats.MaxWait(FetchWait))
+			if err == nats.ErrTimeout {
+				continue
+			}
+			if err == nats.ErrBadSubscription || err == nats.ErrConnectionClosed {
+				j.exporter.DecConsumer(service, name)
+				log.Error().Str("service", service).Str("name", name).Msgf("consumer stopped. %v", err)
+				return
+			}
+			if err != nil {
+				log.Error().Str("service", service).Str("name", name).Msgf("failed to fetch messages. %v", err)
+				time.Sleep(WaitToReconnectDuration)
+				continue
+			}
+			for _, m := range messages {
+				done := make(chan struct{})
+				go inProgress(m, done)
+				handlers <- struct{}{}
+				go func(m *nats.Msg) {
+					defer func() { <-handlers }()
+					defer close(done)
+					j.handle(m, service, name, consumerName, queueName, callback)
+				}(m)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// inProgress resets the ack wait of the message until done is closed. The fetched messages wait for a free handler,
+// the rate limiter and the lane of their order key, they would be redelivered as duplicates after the ack wait.
+func inProgress(m *nats.Msg, done <-chan struct{}) {
+	ticker := time.NewTicker(InProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := m.InProgress(); err != nil {
+				log.Error().Msgf("failed to send in progress. %v", err)
+			}
+		}
+	}
+}
+
+// handle acknowledges the message on its own, the other handlers do not wait for it.
+func (j *JetStream) handle(m *nats.Msg, service string, name string, consumerName string, queueName string, callback messaging.Callback) {
+	start := time.Now()
+	d := &messaging.Delivery{Body: m.Data, Attempt: 1}
+	if meta, err := m.Metadata(); err == nil {
+		d.Attempt = int(meta.NumDelivered)
+		d.QueuedAt = meta.Timestamp
+	}
+	result := j.invokeConsumerFunc(d, callback)
+	switch result.Action {
+	case messaging.Retry:
+		// redelivered by the server after the delay, like the dead lettered messages of the retry queue.
+		if err := m.NakWithDelay(result.Delay); err != nil {
+			log.Error().Msgf("failed to send NAK. %v", err)
+		}
+		j.exporter.IncConsumeError(service, name)
+	case messaging.Dead:
+		l := messaging.NewDeadLetter(service, name, queueName, d, result.Failure)
+		if err := j.deadLetter(consumerName, l); err != nil {
+			log.Error().Msgf("failed to publish to dead letter stream. %v", err)
+			if err := m.NakWithDelay(RetryDelay); err != nil {
+				log.Error().Msgf("failed to send NAK. %v", err)
+			}
+		} else if err := m.Ack(); err != nil {
+			log.Error().Msgf("failed to send ACK. %v", err)
+		}
+		j.exporter.IncConsumeError(service, name)
+	default:
+		if err := m.Ack(); err != nil {
+			log.Error().Msgf("failed to send ACK. %v", err)
+		}
+	}
+	j.exporter.ConsumeSeconds(time.Since(start), service, name)
+}
+
+func (j *JetStream) invokeConsumerFunc(d *messaging.Delivery, callback messaging.Callback) (result messaging.Result) {
+	defer func() {
+		if rc := recover(); rc != nil {
+			log.Error().Msgf("error when invoking the consumer function: %+v", rc)
+			result = messaging.Result{Action: messaging.Retry, Delay: RetryDelay}
+		}
+	}()
+	if callback == nil {
+		log.Warn().Msgf("callback function is nil. Make sure you have correct setup for your consumer")
+		return messaging.Result{Action: messaging.Retry, Delay: RetryDelay}
+	}
+	return callback(d)
+}
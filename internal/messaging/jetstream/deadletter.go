@@ -0,0 +1,156 @@
+package jetstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+func (j *JetStream) deadLetter(consumerName string, l *messaging.DeadLetter) error {
+	payload, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return j.send(DeadLettersStreamName, j.getDeadLettersSubject(consumerName), payload)
+}
+
+// DeadLetterQueues .
+func (j *JetStream) DeadLetterQueues() ([]*messaging.DeadLetterQueue, error) {
+	js, err := j.jetStream(DeadLettersStreamName)
+	if err != nil {
+		return nil, err
+	}
+	info, err := js.StreamInfo(DeadLettersStreamName, &nats.StreamInfoRequest{SubjectsFilter: DeadLettersSubjectPrefix + ".>"})
+	if err != nil {
+		return nil, err
+	}
+	var queues []*messaging.DeadLetterQueue
+	for subject, n := range info.State.Subjects {
+		// the subject has the durable consumer name without dots, the dead letters keep the queue name.
+		m, err := js.GetLastMsg(DeadLettersStreamName, subject)
+		if err != nil {
+			return nil, err
+		}
+		l, err := readDeadLetter(m.Sequence, m.Data)
+		if err != nil {
+			return nil, err
+		}
+		queues = append(queues, &messaging.DeadLetterQueue{Queue: l.Queue, Count: int64(n)})
+	}
+	sort.Slice(queues, func(i, k int) bool { return queues[i].Queue < queues[k].Queue })
+	return queues, nil
+}
+
+// PeekDeadLetters reads the oldest dead letters of the queue with an ordered consumer.
+func (j *JetStream) PeekDeadLetters(queue string, limit int) ([]*messaging.DeadLetter, error) {
+	js, err := j.jetStream(DeadLettersStreamName)
+	if err != nil {
+		return nil, err
+	}
+	subject := j.getDeadLettersSubject(j.getQueueConsumerName(queue))
+	info, err := js.StreamInfo(DeadLettersStreamName, &nats.StreamInfoRequest{SubjectsFilter: subject})
+	if err != nil {
+		return nil, err
+	}
+	if info.State.Subjects[subject] == 0 {
+		return nil, nil
+	}
+	subscription, err := js.SubscribeSync(subject, nats.BindStream(DeadLettersStreamName), nats.OrderedConsumer(), nats.DeliverAll())
+	if err != nil {
+		return nil, err
+	}
+	defer subscription.Unsubscribe()
+	var letters []*messaging.DeadLetter
+	for limit <= 0 || len(letters) < limit {
+		m, err := subscription.NextMsg(FetchWait)
+		if err != nil {
+			return nil, err
+		}
+		meta, err := m.Metadata()
+		if err != nil {
+			return nil, err
+		}
+		l, err := readDeadLetter(meta.Sequence.Stream, m.Data)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, l)
+		if meta.NumPending == 0 {
+			break
+		}
+	}
+	return letters, nil
+}
+
+// ReplayDeadLetter sends the dead letter of a queue back to the queue. Dead letters of events can not be replayed,
+// every subscriber of the event would get the message again.
+func (j *JetStream) ReplayDeadLetter(queue string, id string, message []byte) error {
+	seq, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return messaging.ErrDeadLetterNotFound
+	}
+	js, err := j.jetStream(DeadLettersStreamName)
+	if err != nil {
+		return err
+	}
+	m, err := js.GetMsg(DeadLettersStreamName, seq)
+	if err == nats.ErrMsgNotFound {
+		return messaging.ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return err
+	}
+	l, err := readDeadLetter(m.Sequence, m.Data)
+	if err != nil {
+		return err
+	}
+	if l.Queue != queue {
+		return messaging.ErrDeadLetterNotFound
+	}
+	if l.Queue != l.Name {
+		return fmt.Errorf("dead letters of events can not be replayed on the nats broker")
+	}
+	if message == nil {
+		message = l.Body
+	}
+	if err := j.send(QueuesStreamName, j.getSubject(l.Name, false), message); err != nil {
+		return err
+	}
+	return js.DeleteMsg(DeadLettersStreamName, seq)
+}
+
+// PurgeDeadLetters .
+func (j *JetStream) PurgeDeadLetters(queue string) (int64, error) {
+	js, err := j.jetStream(DeadLettersStreamName)
+	if err != nil {
+		return 0, err
+	}
+	subject := j.getDeadLettersSubject(j.getQueueConsumerName(queue))
+	info, err := js.StreamInfo(DeadLettersStreamName, &nats.StreamInfoRequest{SubjectsFilter: subject})
+	if err != nil {
+		return 0, err
+	}
+	if err := js.PurgeStream(DeadLettersStreamName, &nats.StreamPurgeRequest{Subject: subject}); err != nil {
+		return 0, err
+	}
+	return int64(info.State.Subjects[subject]), nil
+}
+
+// getQueueConsumerName returns the durable consumer name of the queue name. e.g. order_created.billing
+func (j *JetStream) getQueueConsumerName(queue string) string {
+	return strings.ReplaceAll(queue, ".", "_")
+}
+
+func readDeadLetter(seq uint64, data []byte) (*messaging.DeadLetter, error) {
+	l := &messaging.DeadLetter{}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, err
+	}
+	l.ID = strconv.FormatUint(seq, 10)
+	return l, nil
+}
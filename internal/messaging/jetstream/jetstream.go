@@ -0,0 +1,192 @@
+package jetstream
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/messaging"
+	"github.com/turgayozgur/messageman/internal/metrics"
+)
+
+const (
+	// QueuesStreamName constant. Work-queue stream that keeps the queued jobs.
+	QueuesStreamName = "MESSAGEMAN_QUEUES"
+	// EventsStreamName constant. Interest stream that keeps the published events.
+	EventsStreamName = "MESSAGEMAN_EVENTS"
+	// QueuesSubjectPrefix constant.
+	QueuesSubjectPrefix = "messageman.queues"
+	// EventsSubjectPrefix constant.
+	EventsSubjectPrefix = "messageman.events"
+	// RepliesSubjectPrefix constant. The replies are sent on core NATS subjects, no stream keeps them.
+	RepliesSubjectPrefix = "messageman.replies"
+	// DeadLettersStreamName constant. Limits stream that keeps the dead letters of every consumer.
+	DeadLettersStreamName = "MESSAGEMAN_DEAD_LETTERS"
+	// DeadLettersSubjectPrefix constant.
+	DeadLettersSubjectPrefix = "messageman.dead"
+	// ScheduledStreamName constant. Work-queue stream that keeps the messages due later.
+	ScheduledStreamName = "MESSAGEMAN_SCHEDULED"
+	// ScheduledSubjectPrefix constant. Followed by the subject the message is moved to once due.
+	ScheduledSubjectPrefix = "messageman.scheduled"
+	// SchedulerConsumerName constant. The durable consumer shared by every messageman instance.
+	SchedulerConsumerName = "messageman_scheduler"
+	// SchedulerBatchSize constant. Scheduled messages fetched at once.
+	SchedulerBatchSize = 100
+	// DeliverAtHeader constant. Unix milliseconds the scheduled message is due.
+	DeliverAtHeader = "Messageman-Deliver-At"
+	// TicksBucketName constant. Key-value bucket that keeps the claimed ticks of the schedules.
+	TicksBucketName = "MESSAGEMAN_TICKS"
+	// TickRetention constant. How long the claimed ticks of the schedules are kept.
+	TickRetention = 24 * time.Hour
+	// RetryDelay constant. Matches the TTL of the RabbitMQ retry queue. Used when the callback fails unexpectedly.
+	RetryDelay = 30 * time.Second
+	// AckWait constant. Must be longer than the request timeout of the workers and subscribers.
+	AckWait = 2 * time.Minute
+	// InProgressInterval constant. How often the ack wait of a message that waits or is handled is reset.
+	InProgressInterval = AckWait / 4
+	// FetchWait constant.
+	FetchWait = 5 * time.Second
+	// WaitToReconnectDuration constant.
+	WaitToReconnectDuration = 5 * time.Second
+	// ConnectionName constant.
+	ConnectionName = "messageman"
+)
+
+// JetStream messager
+type JetStream struct {
+	exporter  metrics.Exporter
+	recover   chan string
+	mu        sync.Mutex
+	conn      *nats.Conn
+	js        nats.JetStreamContext
+	streams   map[string]bool
+	scheduler sync.Once
+	ticks     nats.KeyValue
+}
+
+// New ctor
+func New(exporter metrics.Exporter) *JetStream {
+	return &JetStream{
+		exporter: exporter,
+		streams:  make(map[string]bool),
+	}
+}
+
+// EnsureCanConnect to ensure connection is available to the NATS server
+func (j *JetStream) EnsureCanConnect() bool {
+	conn, err := nats.Connect(config.Cfg.NATS.Url, nats.Name(ConnectionName))
+	if err != nil {
+		log.Error().Msgf("failed to connect to the NATS server. url:%s error:%v", config.Cfg.NATS.Url, err)
+		return false
+	}
+	conn.Close()
+	log.Info().Msg("successfully connected to the NATS server")
+	return true
+}
+
+// NotifyRecover .
+func (j *JetStream) NotifyRecover(ch chan string) chan string {
+	j.recover = ch
+	return j.recover
+}
+
+// Queue .
+func (j *JetStream) Queue(service string, name string, message []byte) error {
+	return j.QueueAt(service, name, message, time.Time{})
+}
+
+// QueueAt .
+func (j *JetStream) QueueAt(service string, name string, message []byte, at time.Time) (err error) {
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			log.Error().Err(err).Msg("could not be queued")
+			j.exporter.IncSendError(service, name)
+		}
+		j.exporter.SendSeconds(time.Since(start), service, name)
+	}()
+	if err := j.scheduleSend(QueuesStreamName, j.getSubject(name, false), message, at); err != nil {
+		return err
+	}
+	log.Debug().Str("name", name).Msgf("queued")
+	return nil
+}
+
+// Work .
+func (j *JetStream) Work(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) error {
+	if err := j.consume(service, name, callback, opts, false); err != nil {
+		return err
+	}
+	j.exporter.IncConsumer(service, name)
+	return nil
+}
+
+// Publish .
+func (j *JetStream) Publish(service string, name string, message []byte) error {
+	return j.PublishAt(service, name, message, time.Time{})
+}
+
+// PublishAt .
+func (j *JetStream) PublishAt(service string, name string, message []byte, at time.Time) (err error) {
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			log.Error().Err(err).Msg("could not be published")
+			j.exporter.IncSendError(service, name)
+		}
+		j.exporter.SendSeconds(time.Since(start), service, name)
+	}()
+	if err := j.scheduleSend(EventsStreamName, j.getSubject(name, true), message, at); err != nil {
+		return err
+	}
+	log.Debug().Str("name", name).Msgf("published")
+	return nil
+}
+
+// Subscribe .
+func (j *JetStream) Subscribe(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) error {
+	if err := j.consume(service, name, callback, opts, true); err != nil {
+		return err
+	}
+	j.exporter.IncConsumer(service, name)
+	return nil
+}
+
+func (j *JetStream) send(stream string, subject string, message []byte) error {
+	js, err := j.jetStream(stream)
+	if err != nil {
+		return err
+	}
+	if _, err := js.Publish(subject, message); err != nil {
+		return fmt.Errorf("failed to publish a message. %v", err)
+	}
+	return nil
+}
+
+func (j *JetStream) getSubject(name string, pubSub bool) string {
+	if pubSub {
+		return fmt.Sprintf("%s.%s", EventsSubjectPrefix, name)
+	}
+	return fmt.Sprintf("%s.%s", QueuesSubjectPrefix, name)
+}
+
+// getDeadLettersSubject returns the subject of the dead letters of the durable consumer.
+func (j *JetStream) getDeadLettersSubject(consumerName string) string {
+	return fmt.Sprintf("%s.%s", DeadLettersSubjectPrefix, consumerName)
+}
+
+// getConsumerName returns the durable consumer name. One consumer per queue, one per subscriber service for events.
+func (j *JetStream) getConsumerName(service string, name string, pubSub bool) string {
+	var consumerName string
+	if pubSub {
+		consumerName = fmt.Sprintf("%s.%s", name, service)
+	} else {
+		consumerName = name
+	}
+	// durable names can not contain dots.
+	return strings.ReplaceAll(consumerName, ".", "_")
+}
@@ -0,0 +1,52 @@
+package jetstream
+
+import (
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+// ListenReplies subscribes to the reply subject of the queue. The subscription goes away with the connection and is
+// renewed by the client once the connection is recovered, the replies sent meanwhile are dropped.
+func (j *JetStream) ListenReplies(queue string, callback messaging.Callback) error {
+	conn, err := j.core()
+	if err != nil {
+		return err
+	}
+	_, err = conn.Subscribe(j.getReplySubject(queue), func(m *nats.Msg) {
+		callback(&messaging.Delivery{Body: m.Data, Attempt: 1})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to the replies. %v", err)
+	}
+	return nil
+}
+
+// Reply publishes the message on the reply subject of the queue.
+func (j *JetStream) Reply(queue string, message []byte) error {
+	conn, err := j.core()
+	if err != nil {
+		return err
+	}
+	if err := conn.Publish(j.getReplySubject(queue), message); err != nil {
+		return fmt.Errorf("failed to publish a reply. %v", err)
+	}
+	return nil
+}
+
+// core returns the shared connection without the JetStream context.
+func (j *JetStream) core() (*nats.Conn, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.conn == nil {
+		if err := j.connect(); err != nil {
+			return nil, err
+		}
+	}
+	return j.conn, nil
+}
+
+func (j *JetStream) getReplySubject(queue string) string {
+	return fmt.Sprintf("%s.%s", RepliesSubjectPrefix, queue)
+}
@@ -0,0 +1,119 @@
+package jetstream
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+// scheduleSend sends the message to the stream, or to the scheduled stream if it is due later.
+func (j *JetStream) scheduleSend(stream string, subject string, message []byte, at time.Time) error {
+	if !at.After(time.Now()) {
+		return j.send(stream, subject, message)
+	}
+	js, err := j.jetStream(ScheduledStreamName)
+	if err != nil {
+		return err
+	}
+	j.startScheduler()
+	m := nats.NewMsg(j.getScheduledSubject(subject))
+	m.Data = message
+	m.Header.Set(DeliverAtHeader, strconv.FormatInt(at.UnixNano()/int64(time.Millisecond), 10))
+	if _, err := js.PublishMsg(m); err != nil {
+		return fmt.Errorf("failed to publish a message. %v", err)
+	}
+	return nil
+}
+
+// startScheduler starts the scheduler once, by the first consumer or the first scheduled message.
+func (j *JetStream) startScheduler() {
+	j.scheduler.Do(func() {
+		go j.runScheduler()
+	})
+}
+
+// runScheduler moves the scheduled messages to their streams once they are due. A message that is not due yet is
+// redelivered by the server after the remaining time, every messageman instance shares the same durable consumer.
+func (j *JetStream) runScheduler() {
+	var subscription *nats.Subscription
+	for {
+		js, err := j.jetStream(ScheduledStreamName)
+		if err == nil {
+			subscription, err = js.PullSubscribe(
+				ScheduledSubjectPrefix+".>",
+				SchedulerConsumerName,
+				nats.BindStream(ScheduledStreamName),
+				nats.AckExplicit(),
+				nats.AckWait(AckWait),
+				nats.MaxDeliver(-1),
+				// messages waiting for their time must not block the ones due earlier.
+				nats.MaxAckPending(-1),
+			)
+		}
+		if err == nil {
+			break
+		}
+		log.Error().Msgf("failed to start the scheduler. %v", err)
+		time.Sleep(WaitToReconnectDuration)
+	}
+
+	for {
+		messages, err := subscription.Fetch(SchedulerBatchSize, nats.MaxWait(FetchWait))
+		if err == nats.ErrTimeout {
+			continue
+		}
+		if err == nats.ErrBadSubscription || err == nats.ErrConnectionClosed {
+			log.Error().Msgf("scheduler stopped. %v", err)
+			return
+		}
+		if err != nil {
+			log.Error().Msgf("failed to fetch scheduled messages. %v", err)
+			time.Sleep(WaitToReconnectDuration)
+			continue
+		}
+		for _, m := range messages {
+			j.moveScheduled(m)
+		}
+	}
+}
+
+func (j *JetStream) moveScheduled(m *nats.Msg) {
+	ms, err := strconv.ParseInt(m.Header.Get(DeliverAtHeader), 10, 64)
+	if err != nil {
+		log.Error().Str("subject", m.Subject).Msgf("scheduled message without a valid due time, dropped. %v", err)
+		if err := m.Term(); err != nil {
+			log.Error().Msgf("failed to send TERM. %v", err)
+		}
+		return
+	}
+	if delay := time.Until(time.Unix(0, ms*int64(time.Millisecond))); delay > 0 {
+		if err := m.NakWithDelay(delay); err != nil {
+			log.Error().Msgf("failed to send NAK. %v", err)
+		}
+		return
+	}
+	subject := strings.TrimPrefix(m.Subject, ScheduledSubjectPrefix+".")
+	stream := QueuesStreamName
+	if strings.HasPrefix(subject, EventsSubjectPrefix+".") {
+		stream = EventsStreamName
+	}
+	if err := j.send(stream, subject, m.Data); err != nil {
+		log.Error().Str("subject", subject).Msgf("failed to move the scheduled message. %v", err)
+		if err := m.NakWithDelay(WaitToReconnectDuration); err != nil {
+			log.Error().Msgf("failed to send NAK. %v", err)
+		}
+		return
+	}
+	if err := m.Ack(); err != nil {
+		log.Error().Msgf("failed to send ACK. %v", err)
+	}
+}
+
+// getScheduledSubject returns the scheduled subject of the subject. e.g. messageman.scheduled.messageman.queues.send_email
+func (j *JetStream) getScheduledSubject(subject string) string {
+	return fmt.Sprintf("%s.%s", ScheduledSubjectPrefix, subject)
+}
@@ -0,0 +1,55 @@
+package jetstream
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// LockTick creates the key of the tick on the ticks bucket, only one replica succeeds. The key expires on its own.
+func (j *JetStream) LockTick(schedule string, tick time.Time) (bool, error) {
+	kv, err := j.ticksBucket()
+	if err != nil {
+		return false, err
+	}
+	_, err = kv.Create(j.getTickKey(schedule, tick), nil)
+	if err == nats.ErrKeyExists {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ticksBucket returns the ticks bucket, creates it if not exists.
+func (j *JetStream) ticksBucket() (nats.KeyValue, error) {
+	js, err := j.jetStream(QueuesStreamName)
+	if err != nil {
+		return nil, err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.ticks != nil {
+		return j.ticks, nil
+	}
+	kv, err := js.KeyValue(TicksBucketName)
+	if err == nats.ErrBucketNotFound {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:  TicksBucketName,
+			TTL:     TickRetention,
+			Storage: nats.FileStorage,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to declare the ticks bucket. %v", err)
+	}
+	j.ticks = kv
+	return kv, nil
+}
+
+// getTickKey returns the key of the tick. e.g. nightly_report.1614556800
+func (j *JetStream) getTickKey(schedule string, tick time.Time) string {
+	return fmt.Sprintf("%s.%d", schedule, tick.Unix())
+}
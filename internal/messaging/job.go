@@ -0,0 +1,75 @@
+package messaging
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/internal/jobs"
+)
+
+// JobIDHeader is the ID of a queued job, the status of the job is tracked by it.
+const JobIDHeader = "x-job-id"
+
+// NewJobID returns a random job ID.
+func NewJobID() string {
+	return randomID()
+}
+
+// trackJob saves the status of the job before and after every attempt. The deliveries of a revoked job, the retries
+// and the scheduled ones too, are acked without calling the worker. The messages without a job ID, like the requests
+// and the replayed dead letters of the older versions, are not tracked.
+func trackJob(store jobs.Store, w Wrapper, name string, callback Callback) Callback {
+	if store == nil {
+		return callback
+	}
+	return func(d *Delivery) Result {
+		_, headers, err := w.Unwrap(d.Body)
+		id := string(headers[JobIDHeader])
+		if err != nil || id == "" {
+			return callback(d)
+		}
+		if job, err := store.Get(id); err == nil && job.Status == jobs.Revoked {
+			log.Debug().Str("id", id).Str("name", name).Int("attempt", d.Attempt).Msg("job revoked. Acked without calling the worker")
+			return Result{Action: Ack}
+		}
+		updateJob(store, id, name, d, jobs.InProgress, nil)
+		result := callback(d)
+		updateJob(store, id, name, d, jobStatus(result), result.Failure)
+		return result
+	}
+}
+
+// jobStatus returns the status of the job after the attempt.
+func jobStatus(result Result) string {
+	switch {
+	case result.Action == Retry:
+		return jobs.Retrying
+	case result.Action == Dead:
+		return jobs.Dead
+	case result.Failure != nil:
+		return jobs.Failed
+	}
+	return jobs.Succeeded
+}
+
+// updateJob saves the status of the job. A job missing on the store, like an expired one, is saved again.
+func updateJob(store jobs.Store, id string, name string, d *Delivery, status string, f *Failure) {
+	job, err := store.Get(id)
+	if err == jobs.ErrNotFound {
+		job, err = &jobs.Job{ID: id, Name: name, QueuedAt: d.QueuedAt}, nil
+	}
+	if err != nil {
+		log.Error().Err(err).Str("id", id).Str("name", name).Msg("failed to read the job")
+		return
+	}
+	job.Status = status
+	job.Attempt = d.Attempt
+	job.Error = ""
+	if f != nil {
+		job.Error = f.Error
+	}
+	job.UpdatedAt = time.Now()
+	if err := store.Save(job); err != nil {
+		log.Error().Err(err).Str("id", id).Str("name", name).Msg("failed to save the job")
+	}
+}
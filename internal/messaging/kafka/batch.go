@@ -0,0 +1,61 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+// QueueBatch .
+func (k *Kafka) QueueBatch(service string, messages []*messaging.BatchMessage) []error {
+	return k.sendBatch(service, messages, "queued")
+}
+
+// PublishBatch .
+func (k *Kafka) PublishBatch(service string, messages []*messaging.BatchMessage) []error {
+	return k.sendBatch(service, messages, "published")
+}
+
+// sendBatch writes the messages to their topics with a single write.
+func (k *Kafka) sendBatch(service string, messages []*messaging.BatchMessage, verb string) []error {
+	start := time.Now()
+	errs := make([]error, len(messages))
+	var batch []kafkago.Message
+	var indexes []int
+	for i, m := range messages {
+		if err := k.topic(m.Name); err != nil {
+			errs[i] = err
+			continue
+		}
+		batch = append(batch, kafkago.Message{Topic: m.Name, Key: k.partitionKey(m.Message), Value: m.Message})
+		indexes = append(indexes, i)
+	}
+	if len(batch) > 0 {
+		err := k.producer().WriteMessages(context.Background(), batch...)
+		if writeErrs, ok := err.(kafkago.WriteErrors); ok {
+			for j, e := range writeErrs {
+				if e != nil {
+					errs[indexes[j]] = fmt.Errorf("failed to publish a message. %v", e)
+				}
+			}
+		} else if err != nil {
+			for _, i := range indexes {
+				errs[i] = fmt.Errorf("failed to publish a message. %v", err)
+			}
+		}
+	}
+	elapsed := time.Since(start)
+	for i, m := range messages {
+		if errs[i] != nil {
+			log.Error().Err(errs[i]).Str("name", m.Name).Msgf("could not be %s", verb)
+			k.exporter.IncSendError(service, m.Name)
+		}
+		k.exporter.SendSeconds(elapsed/time.Duration(len(messages)), service, m.Name)
+	}
+	log.Debug().Int("count", len(messages)).Msgf("batch %s", verb)
+	return errs
+}
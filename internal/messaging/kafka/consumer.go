@@ -0,0 +1,185 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+// consume starts the readers of the main topic and the readers of the retry chain. The offset
+// of a message is committed only after the callback succeeds or the message is handed over
+// to the next retry topic.
+//
+// Offsets of a partition are committed in order, so a partition is never handled concurrently. The concurrency
+// is the number of readers of the main topic in the consumer group, bounded by the partition count of the topic.
+func (k *Kafka) consume(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions, pubSub bool) error {
+	groupID := k.getGroupID(service, name, pubSub)
+	if err := k.topic(name); err != nil {
+		return err
+	}
+	k.startScheduler()
+	for level := 1; level <= RetryTopicCount; level++ {
+		if err := k.topic(k.getRetryTopicName(groupID, level)); err != nil {
+			return err
+		}
+	}
+
+	// new subscribers start from the latest events like a newly bound queue, workers take over the waiting jobs.
+	startOffset := kafkago.FirstOffset
+	if pubSub {
+		startOffset = kafkago.LastOffset
+	}
+	for i := 0; i < opts.Concurrency; i++ {
+		go k.read(k.reader(name, groupID, startOffset, opts.Prefetch), service, name, groupID, 0, callback)
+	}
+	for level := 1; level <= RetryTopicCount; level++ {
+		retryTopic := k.getRetryTopicName(groupID, level)
+		go k.read(k.reader(retryTopic, retryTopic, kafkago.FirstOffset, opts.Prefetch), service, name, groupID, level, callback)
+	}
+	return nil
+}
+
+// reader creates a reader of the consumer group. The reader keeps as many fetched messages as the prefetch
+// count, 0 leaves it to the default of the reader.
+func (k *Kafka) reader(topic string, groupID string, startOffset int64, prefetch int) *kafkago.Reader {
+	return kafkago.NewReader(kafkago.ReaderConfig{
+		Brokers:       config.Cfg.Kafka.Brokers,
+		GroupID:       groupID,
+		Topic:         topic,
+		StartOffset:   startOffset,
+		MinBytes:      1,
+		MaxBytes:      10e6,
+		MaxWait:       time.Second,
+		QueueCapacity: prefetch,
+	})
+}
+
+func (k *Kafka) read(reader *kafkago.Reader, service string, name string, groupID string, level int, callback messaging.Callback) {
+	ctx := context.Background()
+	for {
+		m, err := reader.FetchMessage(ctx)
+		if err != nil {
+			k.exporter.IncError(service)
+			log.Error().Str("service", service).Str("name", name).Msgf("failed to fetch messages. %v", err)
+			time.Sleep(WaitToReconnectDuration)
+			continue
+		}
+		// messages of a retry topic are on the same attempt, so they are mostly due in the order they are read.
+		if due := retryAt(m); time.Now().Before(due) {
+			time.Sleep(time.Until(due))
+		}
+		start := time.Now()
+		d := &messaging.Delivery{Body: m.Value, Attempt: attempt(m), QueuedAt: m.Time}
+		result := k.invokeConsumerFunc(d, callback)
+		switch result.Action {
+		case messaging.Retry:
+			k.retry(groupID, level, m, result.Delay)
+			k.exporter.IncConsumeError(service, name)
+		case messaging.Dead:
+			k.deadLetter(groupID, m, messaging.NewDeadLetter(service, name, groupID, d, result.Failure))
+			k.exporter.IncConsumeError(service, name)
+		}
+		for {
+			if err := reader.CommitMessages(ctx, m); err != nil {
+				log.Error().Msgf("failed to commit offset. %v", err)
+				time.Sleep(WaitToReconnectDuration)
+				continue
+			}
+			break
+		}
+		k.exporter.ConsumeSeconds(time.Since(start), service, name)
+	}
+}
+
+// retry hands the message over to the next topic of the retry chain. The last retry topic retries to itself.
+// It blocks until the message is written, so the offset of a failed message is never committed before.
+func (k *Kafka) retry(groupID string, level int, m kafkago.Message, delay time.Duration) {
+	next := level + 1
+	if next > RetryTopicCount {
+		next = RetryTopicCount
+	}
+	headers := []kafkago.Header{
+		{Key: AttemptHeader, Value: []byte(strconv.Itoa(attempt(m) + 1))},
+		{Key: RetryAtHeader, Value: []byte(strconv.FormatInt(time.Now().Add(delay).UnixNano()/int64(time.Millisecond), 10))},
+	}
+	for {
+		if err := k.send(k.getRetryTopicName(groupID, next), m.Key, m.Value, headers); err != nil {
+			log.Error().Msgf("failed to publish to retry topic. %v", err)
+			time.Sleep(WaitToReconnectDuration)
+			continue
+		}
+		return
+	}
+}
+
+// deadLetter hands the message over to the dead letter topic of the consumer group, the failure travels on the headers.
+// It blocks until the message is written like the retries.
+func (k *Kafka) deadLetter(groupID string, m kafkago.Message, l *messaging.DeadLetter) {
+	headers := []kafkago.Header{
+		{Key: AttemptHeader, Value: []byte(strconv.Itoa(l.Attempt))},
+		{Key: StatusCodeHeader, Value: []byte(strconv.Itoa(l.StatusCode))},
+		{Key: ErrorHeader, Value: []byte(l.Error)},
+		{Key: FailedAtHeader, Value: []byte(strconv.FormatInt(l.FailedAt.UnixNano()/int64(time.Millisecond), 10))},
+	}
+	for {
+		if err := k.send(k.getDeadTopicName(groupID), m.Key, m.Value, headers); err != nil {
+			log.Error().Msgf("failed to publish to dead letter topic. %v", err)
+			time.Sleep(WaitToReconnectDuration)
+			continue
+		}
+		return
+	}
+}
+
+func (k *Kafka) invokeConsumerFunc(d *messaging.Delivery, callback messaging.Callback) (result messaging.Result) {
+	defer func() {
+		if rc := recover(); rc != nil {
+			log.Error().Msgf("error when invoking the consumer function: %+v", rc)
+			result = messaging.Result{Action: messaging.Retry, Delay: RetryDelay}
+		}
+	}()
+	if callback == nil {
+		log.Warn().Msgf("callback function is nil. Make sure you have correct setup for your consumer")
+		return messaging.Result{Action: messaging.Retry, Delay: RetryDelay}
+	}
+	return callback(d)
+}
+
+// attempt returns the delivery attempt of the message, starting from 1.
+func attempt(m kafkago.Message) int {
+	for _, h := range m.Headers {
+		if h.Key == AttemptHeader {
+			if v, err := strconv.Atoi(string(h.Value)); err == nil {
+				return v
+			}
+		}
+	}
+	return 1
+}
+
+func retryAt(m kafkago.Message) time.Time {
+	for _, h := range m.Headers {
+		if h.Key == RetryAtHeader {
+			if v, err := strconv.ParseInt(string(h.Value), 10, 64); err == nil {
+				return time.Unix(0, v*int64(time.Millisecond))
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// CheckOrdering accepts the ordering on the partition key header. Messages of the same key are on the same partition
+// and a partition is handled by a single reader in order.
+func (k *Kafka) CheckOrdering(opts messaging.ConsumeOptions) error {
+	header := config.Cfg.Kafka.PartitionKeyHeader
+	if header == "" || opts.Ordering.KeyHeader != header {
+		return fmt.Errorf("the kafka broker orders by the partition key only. The key header must be the partition key header")
+	}
+	return nil
+}
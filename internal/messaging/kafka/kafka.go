@@ -0,0 +1,194 @@
+package kafka
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/messaging"
+	"github.com/turgayozgur/messageman/internal/metrics"
+)
+
+const (
+	// RetryTopicNameSuffix constant.
+	RetryTopicNameSuffix = "retry"
+	// RetryTopicCount constant. The number of retry topics on the retry chain of a consumer group.
+	RetryTopicCount = 3
+	// RetryDelay constant. Matches the TTL of the RabbitMQ retry queue. Used when the callback fails unexpectedly.
+	RetryDelay = 30 * time.Second
+	// DelayTopicNamePrefix constant. Followed by the delay of the topic in seconds.
+	DelayTopicNamePrefix = "messageman.delay"
+	// DelayReaderPrefetch constant. Messages kept by the reader of a delay topic.
+	DelayReaderPrefetch = 100
+	// SchedulerTopicName constant. The replica assigned the first partition of the topic fires the ticks of the schedules.
+	SchedulerTopicName = "messageman.scheduler"
+	// WaitToReconnectDuration constant.
+	WaitToReconnectDuration = 5 * time.Second
+	// AttemptHeader constant.
+	AttemptHeader = "x-attempt"
+	// RetryAtHeader constant. Unix milliseconds of the time the retried message is due.
+	RetryAtHeader = "x-retry-at"
+	// StatusCodeHeader constant.
+	StatusCodeHeader = "x-status-code"
+	// ErrorHeader constant.
+	ErrorHeader = "x-error"
+	// FailedAtHeader constant. Unix milliseconds of the last failed attempt.
+	FailedAtHeader = "x-failed-at"
+	// TopicHeader constant. The topic the scheduled message is moved to once due.
+	TopicHeader = "x-topic"
+	// DeliverAtHeader constant. Unix milliseconds of the time the scheduled message is due.
+	DeliverAtHeader = "x-deliver-at"
+)
+
+// DelayTopicLevels are the delays of the delay topics. A scheduled message waits on the longest one that is not
+// longer than its remaining time, again and again until it is due.
+var DelayTopicLevels = []time.Duration{time.Second, 10 * time.Second, time.Minute, 10 * time.Minute, time.Hour}
+
+// Kafka messager
+type Kafka struct {
+	exporter  metrics.Exporter
+	wrapper   messaging.Wrapper
+	recover   chan string
+	mu        sync.Mutex
+	writer    *kafkago.Writer
+	topics    map[string]bool
+	scheduler sync.Once
+	elector   sync.Once
+	leader    bool
+}
+
+// New ctor. The wrapper is used to read the partition key from the proxied headers of the messages.
+func New(exporter metrics.Exporter, wrapper messaging.Wrapper) *Kafka {
+	return &Kafka{
+		exporter: exporter,
+		wrapper:  wrapper,
+		topics:   make(map[string]bool),
+	}
+}
+
+// EnsureCanConnect to ensure connection is available to one of the kafka brokers
+func (k *Kafka) EnsureCanConnect() bool {
+	for _, broker := range config.Cfg.Kafka.Brokers {
+		conn, err := kafkago.Dial("tcp", broker)
+		if err != nil {
+			log.Error().Msgf("failed to connect to the Kafka broker. host:%s error:%v", broker, err)
+			continue
+		}
+		if err := conn.Close(); err != nil {
+			log.Error().Msgf("failed to close the Kafka connection. %v", err)
+		}
+		log.Info().Msg("successfully connected to the Kafka broker")
+		return true
+	}
+	return false
+}
+
+// NotifyRecover .
+func (k *Kafka) NotifyRecover(ch chan string) chan string {
+	k.recover = ch
+	return k.recover
+}
+
+// Queue .
+func (k *Kafka) Queue(service string, name string, message []byte) error {
+	return k.QueueAt(service, name, message, time.Time{})
+}
+
+// QueueAt .
+func (k *Kafka) QueueAt(service string, name string, message []byte, at time.Time) (err error) {
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			log.Error().Err(err).Msg("could not be queued")
+			k.exporter.IncSendError(service, name)
+		}
+		k.exporter.SendSeconds(time.Since(start), service, name)
+	}()
+	if err := k.schedule(name, k.partitionKey(message), message, at); err != nil {
+		return err
+	}
+	log.Debug().Str("name", name).Msgf("queued")
+	return nil
+}
+
+// Work .
+func (k *Kafka) Work(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) error {
+	if err := k.consume(service, name, callback, opts, false); err != nil {
+		return err
+	}
+	k.exporter.IncConsumer(service, name)
+	return nil
+}
+
+// Publish .
+func (k *Kafka) Publish(service string, name string, message []byte) error {
+	return k.PublishAt(service, name, message, time.Time{})
+}
+
+// PublishAt .
+func (k *Kafka) PublishAt(service string, name string, message []byte, at time.Time) (err error) {
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			log.Error().Err(err).Msg("could not be published")
+			k.exporter.IncSendError(service, name)
+		}
+		k.exporter.SendSeconds(time.Since(start), service, name)
+	}()
+	if err := k.schedule(name, k.partitionKey(message), message, at); err != nil {
+		return err
+	}
+	log.Debug().Str("name", name).Msgf("published")
+	return nil
+}
+
+// Subscribe .
+func (k *Kafka) Subscribe(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) error {
+	if err := k.consume(service, name, callback, opts, true); err != nil {
+		return err
+	}
+	k.exporter.IncConsumer(service, name)
+	return nil
+}
+
+// partitionKey returns the value of the configured proxied header. Messages without the key are balanced round robin.
+func (k *Kafka) partitionKey(message []byte) []byte {
+	header := config.Cfg.Kafka.PartitionKeyHeader
+	if header == "" {
+		return nil
+	}
+	_, headers, err := k.wrapper.Unwrap(message)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to read the partition key of the message")
+		return nil
+	}
+	return headers[header]
+}
+
+// getGroupID returns the consumer group. One group for a queue, one group per subscriber service for events.
+func (k *Kafka) getGroupID(service string, name string, pubSub bool) string {
+	var groupID string
+	if pubSub {
+		groupID = fmt.Sprintf("%s.%s", name, service)
+	} else {
+		groupID = name
+	}
+	return groupID
+}
+
+// getDeadTopicName returns the topic that keeps the dead letters of the consumer group.
+func (k *Kafka) getDeadTopicName(groupID string) string {
+	return messaging.GetDeadLetterQueueName(groupID)
+}
+
+// getDelayTopicName returns the delay topic of the level. e.g. messageman.delay.60
+func (k *Kafka) getDelayTopicName(level int) string {
+	return fmt.Sprintf("%s.%d", DelayTopicNamePrefix, int(DelayTopicLevels[level].Seconds()))
+}
+
+func (k *Kafka) getRetryTopicName(groupID string, level int) string {
+	return fmt.Sprintf("%s.%s.%d", groupID, RetryTopicNameSuffix, level)
+}
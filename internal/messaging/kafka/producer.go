@@ -0,0 +1,83 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/turgayozgur/messageman/config"
+)
+
+func (k *Kafka) send(topic string, key []byte, message []byte, headers []kafkago.Header) error {
+	if err := k.topic(topic); err != nil {
+		return err
+	}
+	err := k.producer().WriteMessages(context.Background(), kafkago.Message{
+		Topic:   topic,
+		Key:     key,
+		Value:   message,
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish a message. %v", err)
+	}
+	return nil
+}
+
+func (k *Kafka) producer() *kafkago.Writer {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.writer == nil {
+		k.writer = &kafkago.Writer{
+			Addr:         kafkago.TCP(config.Cfg.Kafka.Brokers...),
+			Balancer:     &kafkago.Hash{}, // round robin for the messages without a partition key.
+			RequiredAcks: kafkago.RequireAll,
+			BatchTimeout: 10 * time.Millisecond,
+		}
+	}
+	return k.writer
+}
+
+// topic creates the topic on the cluster if not created before by this process.
+func (k *Kafka) topic(name string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.topics[name] {
+		return nil
+	}
+
+	var err error
+	for _, broker := range config.Cfg.Kafka.Brokers {
+		if err = k.createTopic(broker, name); err == nil {
+			k.topics[name] = true
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to declare a topic. %v", err)
+}
+
+func (k *Kafka) createTopic(broker string, name string) error {
+	conn, err := kafkago.Dial("tcp", broker)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	controller, err := conn.Controller()
+	if err != nil {
+		return err
+	}
+	controllerConn, err := kafkago.Dial("tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	if err != nil {
+		return err
+	}
+	defer controllerConn.Close()
+	// an existing topic is not an error.
+	return controllerConn.CreateTopics(kafkago.TopicConfig{
+		Topic:             name,
+		NumPartitions:     config.Cfg.Kafka.Partitions,
+		ReplicationFactor: config.Cfg.Kafka.ReplicationFactor,
+	})
+}
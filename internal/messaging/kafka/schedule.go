@@ -0,0 +1,117 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// schedule sends the message to the topic, or to the delay topics if it is due later.
+func (k *Kafka) schedule(topic string, key []byte, message []byte, at time.Time) error {
+	if !at.After(time.Now()) {
+		return k.send(topic, key, message, nil)
+	}
+	k.startScheduler()
+	headers := []kafkago.Header{
+		{Key: TopicHeader, Value: []byte(topic)},
+		{Key: DeliverAtHeader, Value: []byte(strconv.FormatInt(at.UnixNano()/int64(time.Millisecond), 10))},
+	}
+	return k.send(k.getDelayTopicName(delayLevel(time.Until(at))), key, message, headers)
+}
+
+// startScheduler starts the readers of the delay topics once, by the first consumer or the first scheduled message.
+func (k *Kafka) startScheduler() {
+	k.scheduler.Do(func() {
+		for level := range DelayTopicLevels {
+			go k.readDelayed(level)
+		}
+	})
+}
+
+// readDelayed moves the messages of the delay topic on. Every message of a delay topic waits for the same delay, so
+// they are due in the order they are read. A message is sent to its topic once due, otherwise to the next delay topic.
+// Every messageman instance shares the consumer group of the delay topic.
+func (k *Kafka) readDelayed(level int) {
+	ctx := context.Background()
+	topic := k.getDelayTopicName(level)
+	for {
+		if err := k.topic(topic); err != nil {
+			log.Error().Str("topic", topic).Msgf("failed to start the scheduler. %v", err)
+			time.Sleep(WaitToReconnectDuration)
+			continue
+		}
+		break
+	}
+	reader := k.reader(topic, topic, kafkago.FirstOffset, DelayReaderPrefetch)
+	for {
+		m, err := reader.FetchMessage(ctx)
+		if err != nil {
+			log.Error().Str("topic", topic).Msgf("failed to fetch scheduled messages. %v", err)
+			time.Sleep(WaitToReconnectDuration)
+			continue
+		}
+		if wait := time.Until(m.Time.Add(DelayTopicLevels[level])); wait > 0 {
+			time.Sleep(wait)
+		}
+		target, at := scheduled(m)
+		if target == "" {
+			log.Error().Str("topic", topic).Msg("scheduled message without a topic, dropped")
+		} else if remaining := time.Until(at); remaining < DelayTopicLevels[0] {
+			time.Sleep(remaining)
+			k.moveScheduled(target, m.Key, m.Value, nil)
+		} else {
+			k.moveScheduled(k.getDelayTopicName(delayLevel(remaining)), m.Key, m.Value, m.Headers)
+		}
+		for {
+			if err := reader.CommitMessages(ctx, m); err != nil {
+				log.Error().Msgf("failed to commit offset. %v", err)
+				time.Sleep(WaitToReconnectDuration)
+				continue
+			}
+			break
+		}
+	}
+}
+
+// moveScheduled blocks until the message is written like the retries.
+func (k *Kafka) moveScheduled(topic string, key []byte, message []byte, headers []kafkago.Header) {
+	for {
+		if err := k.send(topic, key, message, headers); err != nil {
+			log.Error().Str("topic", topic).Msgf("failed to move the scheduled message. %v", err)
+			time.Sleep(WaitToReconnectDuration)
+			continue
+		}
+		return
+	}
+}
+
+// delayLevel returns the longest delay topic that is not longer than the delay.
+func delayLevel(delay time.Duration) int {
+	level := 0
+	for i, d := range DelayTopicLevels {
+		if d <= delay {
+			level = i
+		}
+	}
+	return level
+}
+
+// scheduled returns the topic and the due time of the scheduled message.
+func scheduled(m kafkago.Message) (string, time.Time) {
+	var topic string
+	var at time.Time
+	for _, h := range m.Headers {
+		switch h.Key {
+		case TopicHeader:
+			topic = string(h.Value)
+		case DeliverAtHeader:
+			if v, err := strconv.ParseInt(string(h.Value), 10, 64); err == nil {
+				at = time.Unix(0, v*int64(time.Millisecond))
+			}
+		}
+	}
+	return topic, at
+}
@@ -0,0 +1,69 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/turgayozgur/messageman/config"
+)
+
+// LockTick returns true on the leader. The election starts with the first tick, so the ticks right after the start
+// may be skipped by every replica until the consumer group settles.
+func (k *Kafka) LockTick(schedule string, tick time.Time) (bool, error) {
+	k.elector.Do(func() {
+		go k.elect()
+	})
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.leader, nil
+}
+
+// elect joins the consumer group of the scheduler topic. The replica assigned the first partition is the leader
+// until the next rebalance.
+func (k *Kafka) elect() {
+	var group *kafkago.ConsumerGroup
+	for {
+		err := k.topic(SchedulerTopicName)
+		if err == nil {
+			group, err = kafkago.NewConsumerGroup(kafkago.ConsumerGroupConfig{
+				ID:      SchedulerTopicName,
+				Brokers: config.Cfg.Kafka.Brokers,
+				Topics:  []string{SchedulerTopicName},
+			})
+		}
+		if err == nil {
+			break
+		}
+		log.Error().Msgf("failed to join the scheduler group. %v", err)
+		time.Sleep(WaitToReconnectDuration)
+	}
+
+	for {
+		generation, err := group.Next(context.Background())
+		if err != nil {
+			log.Error().Msgf("failed to join the scheduler group. %v", err)
+			time.Sleep(WaitToReconnectDuration)
+			continue
+		}
+		leader := false
+		for _, a := range generation.Assignments[SchedulerTopicName] {
+			if a.ID == 0 {
+				leader = true
+			}
+		}
+		k.setLeader(leader)
+		// the generation ends on the next rebalance.
+		generation.Start(func(ctx context.Context) {
+			<-ctx.Done()
+			k.setLeader(false)
+		})
+	}
+}
+
+func (k *Kafka) setLeader(leader bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.leader = leader
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+func (m *Memory) consume(q *queue, service string, name string, callback messaging.Callback) {
+	for {
+		m.handle(q, q.pop(), service, name, callback)
+	}
+}
+
+// consumeOrdered pops the messages in order and hands them over to the lanes of their keys.
+func (m *Memory) consumeOrdered(q *queue, service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) {
+	lanes := messaging.NewLanes(opts.Concurrency, opts.PrefetchCount())
+	for {
+		msg := q.pop()
+		lanes.Run(opts.Key(msg.body), func() {
+			m.handle(q, msg, service, name, callback)
+		})
+	}
+}
+
+// CheckPattern always succeeds, see send.
+func (m *Memory) CheckPattern(pattern string) error {
+	return nil
+}
+
+// CheckOrdering always succeeds, a single consumer pops the messages of the queue in order.
+func (m *Memory) CheckOrdering(opts messaging.ConsumeOptions) error {
+	return nil
+}
+
+func (m *Memory) handle(q *queue, msg *message, service string, name string, callback messaging.Callback) {
+	start := time.Now()
+	d := &messaging.Delivery{Body: msg.body, Attempt: msg.attempt, QueuedAt: msg.queuedAt, Name: msg.name}
+	result := m.invokeConsumerFunc(d, callback)
+	switch result.Action {
+	case messaging.Retry:
+		retried := &message{body: msg.body, attempt: msg.attempt + 1, queuedAt: msg.queuedAt, name: msg.name}
+		time.AfterFunc(result.Delay, func() {
+			q.push(retried)
+		})
+		m.exporter.IncConsumeError(service, name)
+	case messaging.Dead:
+		m.deadLetter(messaging.NewDeadLetter(service, name, q.name, d, result.Failure))
+		m.exporter.IncConsumeError(service, name)
+	}
+	m.exporter.ConsumeSeconds(time.Since(start), service, name)
+}
+
+func (m *Memory) invokeConsumerFunc(d *messaging.Delivery, callback messaging.Callback) (result messaging.Result) {
+	defer func() {
+		if rc := recover(); rc != nil {
+			log.Error().Msgf("error when invoking the consumer function: %+v", rc)
+			result = messaging.Result{Action: messaging.Retry, Delay: RetryDelay}
+		}
+	}()
+	if callback == nil {
+		log.Warn().Msgf("callback function is nil. Make sure you have correct setup for your consumer")
+		return messaging.Result{Action: messaging.Retry, Delay: RetryDelay}
+	}
+	return callback(d)
+}
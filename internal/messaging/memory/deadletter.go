@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+func (m *Memory) deadLetter(l *messaging.DeadLetter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deadSeq++
+	l.ID = strconv.FormatUint(m.deadSeq, 10)
+	m.dead[l.Queue] = append(m.dead[l.Queue], l)
+}
+
+// DeadLetterQueues .
+func (m *Memory) DeadLetterQueues() ([]*messaging.DeadLetterQueue, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	queues := make([]*messaging.DeadLetterQueue, 0, len(m.dead))
+	for queueName, letters := range m.dead {
+		queues = append(queues, &messaging.DeadLetterQueue{Queue: queueName, Count: int64(len(letters))})
+	}
+	sort.Slice(queues, func(i, j int) bool { return queues[i].Queue < queues[j].Queue })
+	return queues, nil
+}
+
+// PeekDeadLetters returns the oldest dead letters of the queue without removing them.
+func (m *Memory) PeekDeadLetters(queue string, limit int) ([]*messaging.DeadLetter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	letters := m.dead[queue]
+	if limit > 0 && len(letters) > limit {
+		letters = letters[:limit]
+	}
+	return append([]*messaging.DeadLetter{}, letters...), nil
+}
+
+// ReplayDeadLetter .
+func (m *Memory) ReplayDeadLetter(queue string, id string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q, ok := m.queues[queue]
+	if !ok {
+		return messaging.ErrDeadLetterNotFound
+	}
+	letters := m.dead[queue]
+	for i, l := range letters {
+		if l.ID != id {
+			continue
+		}
+		if body == nil {
+			body = l.Body
+		}
+		m.dead[queue] = append(letters[:i:i], letters[i+1:]...)
+		q.push(&message{body: body, attempt: 1, queuedAt: time.Now()})
+		return nil
+	}
+	return messaging.ErrDeadLetterNotFound
+}
+
+// PurgeDeadLetters .
+func (m *Memory) PurgeDeadLetters(queue string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := int64(len(m.dead[queue]))
+	delete(m.dead, queue)
+	return n, nil
+}
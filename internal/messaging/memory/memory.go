@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/internal/messaging"
+	"github.com/turgayozgur/messageman/internal/metrics"
+)
+
+const (
+	// RetryDelay constant. Matches the TTL of the RabbitMQ retry queue. Used when the callback fails unexpectedly.
+	RetryDelay = 30 * time.Second
+)
+
+// Memory messager keeps queues, events and retries in process memory.
+// Nothing survives a restart, it is meant for local development and tests.
+type Memory struct {
+	exporter metrics.Exporter
+	recover  chan string
+	mu       sync.Mutex
+	queues   map[string]*queue
+	bindings map[string][]*queue
+	// patterns are the subscriber queues bound to the events matching a pattern.
+	patterns map[string][]*queue
+	dead     map[string][]*messaging.DeadLetter
+	deadSeq  uint64
+}
+
+// New ctor
+func New(exporter metrics.Exporter) *Memory {
+	return &Memory{
+		exporter: exporter,
+		queues:   make(map[string]*queue),
+		bindings: make(map[string][]*queue),
+		patterns: make(map[string][]*queue),
+		dead:     make(map[string][]*messaging.DeadLetter),
+	}
+}
+
+// EnsureCanConnect always succeeds, there is nothing to connect to.
+func (m *Memory) EnsureCanConnect() bool {
+	return true
+}
+
+// NotifyRecover .
+func (m *Memory) NotifyRecover(ch chan string) chan string {
+	m.recover = ch
+	return m.recover
+}
+
+// Queue .
+func (m *Memory) Queue(service string, name string, message []byte) error {
+	return m.QueueAt(service, name, message, time.Time{})
+}
+
+// QueueAt .
+func (m *Memory) QueueAt(service string, name string, message []byte, at time.Time) error {
+	start := time.Now()
+	defer func() {
+		m.exporter.SendSeconds(time.Since(start), service, name)
+	}()
+	if err := m.send(name, message, at, false); err != nil {
+		log.Error().Err(err).Msg("could not be queued")
+		m.exporter.IncSendError(service, name)
+		return err
+	}
+	log.Debug().Str("name", name).Msgf("queued")
+	return nil
+}
+
+// Work .
+func (m *Memory) Work(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) error {
+	q := m.bind(service, name, false)
+	if opts.Key != nil {
+		go m.consumeOrdered(q, service, name, callback, opts)
+	} else {
+		for i := 0; i < opts.Concurrency; i++ {
+			go m.consume(q, service, name, callback)
+		}
+	}
+	m.exporter.IncConsumer(service, name)
+	return nil
+}
+
+// Publish .
+func (m *Memory) Publish(service string, name string, message []byte) error {
+	return m.PublishAt(service, name, message, time.Time{})
+}
+
+// PublishAt .
+func (m *Memory) PublishAt(service string, name string, message []byte, at time.Time) error {
+	start := time.Now()
+	defer func() {
+		m.exporter.SendSeconds(time.Since(start), service, name)
+	}()
+	if err := m.send(name, message, at, true); err != nil {
+		log.Warn().Str("name", name).Msg("published to no subscribers")
+		return err
+	}
+	log.Debug().Str("name", name).Msgf("published")
+	return nil
+}
+
+// Subscribe .
+func (m *Memory) Subscribe(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) error {
+	q := m.bind(service, name, true)
+	if opts.Key != nil {
+		go m.consumeOrdered(q, service, name, callback, opts)
+	} else {
+		for i := 0; i < opts.Concurrency; i++ {
+			go m.consume(q, service, name, callback)
+		}
+	}
+	m.exporter.IncConsumer(service, name)
+	return nil
+}
+
+// send routes the message to every queue bound to the name, and an event to the subscribers of the matching
+// patterns too. Like a RabbitMQ exchange without bindings, the message is unroutable if no worker or subscriber
+// registered yet. A message due later is held back like a retry.
+func (m *Memory) send(name string, body []byte, at time.Time, pubSub bool) error {
+	m.mu.Lock()
+	bound := append([]*queue{}, m.bindings[name]...)
+	if pubSub {
+		for pattern, queues := range m.patterns {
+			if messaging.MatchTopic(pattern, name) {
+				bound = append(bound, queues...)
+			}
+		}
+	}
+	m.mu.Unlock()
+	if len(bound) == 0 {
+		return messaging.ErrUnroutable
+	}
+	now := time.Now()
+	for _, q := range bound {
+		msg := &message{body: body, attempt: 1, queuedAt: now}
+		if pubSub {
+			msg.name = name
+		}
+		if at.After(now) {
+			q := q
+			time.AfterFunc(at.Sub(now), func() {
+				q.push(msg)
+			})
+			continue
+		}
+		q.push(msg)
+	}
+	return nil
+}
+
+// bind declares the consumer queue if not exists and binds it to the name.
+func (m *Memory) bind(service string, name string, pubSub bool) *queue {
+	queueName := m.getQueueName(service, name, pubSub)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if q, ok := m.queues[queueName]; ok {
+		return q
+	}
+	q := newQueue(queueName)
+	m.queues[queueName] = q
+	if pubSub && messaging.IsPattern(name) {
+		m.patterns[name] = append(m.patterns[name], q)
+		return q
+	}
+	m.bindings[name] = append(m.bindings[name], q)
+	return q
+}
+
+func (m *Memory) getQueueName(service string, name string, pubSub bool) string {
+	var queueName string
+	if pubSub {
+		queueName = fmt.Sprintf("%s.%s", name, service)
+	} else {
+		queueName = name
+	}
+	return queueName
+}
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"sync"
+	"time"
+)
+
+// message is a queued message with its delivery attempt.
+type message struct {
+	body     []byte
+	attempt  int
+	queuedAt time.Time
+	// name is the event the message is published with, empty for the jobs.
+	name string
+}
+
+// queue is an unbounded FIFO shared by the consumers of the same queue name.
+type queue struct {
+	name     string
+	mu       sync.Mutex
+	cond     *sync.Cond
+	messages []*message
+}
+
+func newQueue(name string) *queue {
+	q := &queue{name: name}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *queue) push(m *message) {
+	q.mu.Lock()
+	q.messages = append(q.messages, m)
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// pop blocks until a message is available.
+func (q *queue) pop() *message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.messages) == 0 {
+		q.cond.Wait()
+	}
+	m := q.messages[0]
+	q.messages[0] = nil
+	q.messages = q.messages[1:]
+	return m
+}
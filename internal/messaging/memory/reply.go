@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+// ListenReplies consumes the reply queue. Nothing survives a restart, so the queue goes away with the instance.
+func (m *Memory) ListenReplies(queue string, callback messaging.Callback) error {
+	return m.Work(messaging.ReplyService, queue, callback, messaging.ConsumeOptions{Concurrency: 1})
+}
+
+// Reply sends the message to the reply queue. The message is dropped if no instance listens to the queue.
+func (m *Memory) Reply(queue string, message []byte) error {
+	if err := m.send(queue, message, time.Time{}, false); err == messaging.ErrUnroutable {
+		log.Warn().Str("name", queue).Msg("reply dropped. Nobody listens to the reply queue")
+	}
+	return nil
+}
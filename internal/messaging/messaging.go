@@ -3,22 +3,158 @@ package messaging
 import (
 	"bytes"
 	"context"
+	"errors"
+	"github.com/turgayozgur/messageman/config"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"strconv"
 	"time"
 )
 
+// MaxErrorLength is the maximum length of the response body kept as the failure reason.
+const MaxErrorLength = 1024
+
+var (
+	// ErrUnroutable is returned when the broker can not route the message to any queue, like an event without
+	// subscribers. The message is not kept.
+	ErrUnroutable = errors.New("the message is not routed to any queue")
+	// ErrNacked is returned when the broker could not take the responsibility of the message.
+	ErrNacked = errors.New("the message is rejected by the broker")
+)
+
 // Messager interface
 type Messager interface {
 	EnsureCanConnect() bool
 	NotifyRecover(chan string) chan string
 	Queue(service string, name string, message []byte) error
-	Work(service string, name string, callback func([]byte) bool) error
+	Work(service string, name string, callback Callback, opts ConsumeOptions) error
 	Publish(service string, name string, message []byte) error
-	Subscribe(service string, name string, callback func([]byte) bool) error
+	Subscribe(service string, name string, callback Callback, opts ConsumeOptions) error
+}
+
+// ConsumeOptions are the flow control settings of a worker or a subscriber.
+type ConsumeOptions struct {
+	// Concurrency is the number of deliveries handled at the same time. At least 1.
+	Concurrency int
+	// Prefetch is the number of unacknowledged deliveries the broker sends ahead. 0 if not set.
+	Prefetch int
+	// Ordering is set if the deliveries of the same key must be handled in order. See Orderer.
+	Ordering *config.OrderingConfig
+	// Key returns the ordering key of the message. nil if not ordered.
+	Key func(message []byte) []byte
+}
+
+// NewConsumeOptions reads the flow control settings of the service.
+func NewConsumeOptions(cfg *config.ServiceConfig, w Wrapper) ConsumeOptions {
+	opts := ConsumeOptions{Concurrency: cfg.Concurrency, Prefetch: cfg.Prefetch, Ordering: cfg.Ordering}
+	if cfg.Ordering != nil {
+		opts.Key = orderingKey(cfg.Ordering, w)
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.Prefetch < 0 {
+		opts.Prefetch = 0
+	}
+	// the throttled deliveries wait unacknowledged, fetch no more than the rate limit hands out in time.
+	if n := maxUnacked(cfg.RateLimit); n > 0 {
+		if opts.Concurrency > n {
+			opts.Concurrency = n
+		}
+		if opts.Prefetch > n {
+			opts.Prefetch = n
+		}
+	}
+	return opts
+}
+
+// PrefetchCount returns the prefetch, at least the concurrency so no handler waits for the broker.
+func (o ConsumeOptions) PrefetchCount() int {
+	if o.Prefetch < o.Concurrency {
+		return o.Concurrency
+	}
+	return o.Prefetch
+}
+
+// Scheduler is implemented by the messagers that can hold a message back until the given time.
+type Scheduler interface {
+	QueueAt(service string, name string, message []byte, at time.Time) error
+	PublishAt(service string, name string, message []byte, at time.Time) error
+}
+
+// BatchMessage is a message of a batch, sent to its own queue or event.
+type BatchMessage struct {
+	Name    string
+	Message []byte
+}
+
+// BatchSender is implemented by the messagers that can send many messages at once cheaper than one by one.
+type BatchSender interface {
+	// QueueBatch returns the errors in the order of the messages, nil for the queued ones.
+	QueueBatch(service string, messages []*BatchMessage) []error
+	// PublishBatch returns the errors in the order of the messages, nil for the published ones.
+	PublishBatch(service string, messages []*BatchMessage) []error
+}
+
+// TickLocker is implemented by the messagers that can let a single messageman instance fire the tick of a schedule,
+// so the replicas of a gateway do not queue the same recurring job more than once.
+type TickLocker interface {
+	// LockTick returns true on only one instance for the same schedule and tick.
+	LockTick(schedule string, tick time.Time) (bool, error)
 }
 
-func doRest(wrapper Wrapper, client *http.Client, url string, body []byte) (*http.Response, error) {
+// Delivery is a message handed to a consumer callback.
+type Delivery struct {
+	Body []byte
+	// Attempt starts from 1 and travels with the message on every retry.
+	Attempt int
+	// QueuedAt is the time the message was queued or published. Zero if the broker does not keep it.
+	QueuedAt time.Time
+	// Name is the event the message is published with. Empty if the broker does not keep it.
+	Name string
+	// RetryBy is the latest time the delivery can be retried in place. Zero if it is retried by the broker.
+	RetryBy time.Time
+}
+
+// Action tells the messager what to do with a delivery once the callback returns.
+type Action int
+
+const (
+	// Ack removes the message from the queue.
+	Ack Action = iota
+	// Retry delivers the message again after the delay.
+	Retry
+	// Dead moves the message to the dead letter queue of the consumer.
+	Dead
+)
+
+// Result of a consumer callback.
+type Result struct {
+	Action Action
+	Delay  time.Duration
+	// Failure is the reason of the failed attempt. Kept with the message when it is dead lettered.
+	Failure *Failure
+}
+
+// Failure is the reason a worker or a subscriber could not handle a delivery.
+type Failure struct {
+	// StatusCode is the HTTP or the gRPC status code. 0 if no response received.
+	StatusCode int
+	// GRPC is true if the StatusCode is a gRPC status code.
+	GRPC  bool
+	Error string
+	// RetryAfter is the delay asked by the Retry-After header or the gRPC retry info. 0 if not asked.
+	RetryAfter time.Duration
+}
+
+// Callback handles the deliveries of a worker or a subscriber.
+type Callback func(d *Delivery) Result
+
+func doRest(wrapper Wrapper, client *http.Client, url string, body []byte, extra map[string]string) (*http.Response, error) {
 	body, headers, err := wrapper.Unwrap(body)
 	if err != nil {
 		return nil, err
@@ -30,10 +166,49 @@ func doRest(wrapper Wrapper, client *http.Client, url string, body []byte) (*htt
 	for k, v := range headers {
 		req.Header.Set(k, string(v))
 	}
+	for k, v := range extra {
+		req.Header.Set(k, v)
+	}
 	req.Header.Set("Content-Type", ContentType)
 	return client.Do(req)
 }
 
+// restFailure reads the head of the response body to keep as the failure reason.
+func restFailure(response *http.Response) *Failure {
+	defer response.Body.Close()
+	b, _ := ioutil.ReadAll(io.LimitReader(response.Body, MaxErrorLength))
+	return &Failure{
+		StatusCode: response.StatusCode,
+		Error:      string(b),
+		RetryAfter: parseRetryAfter(response.Header.Get("Retry-After")),
+	}
+}
+
+// grpcFailure reads the retry delay from the RetryInfo detail of the status, if any.
+func grpcFailure(s *status.Status) *Failure {
+	f := &Failure{StatusCode: int(s.Code()), GRPC: true, Error: s.Message()}
+	for _, detail := range s.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.RetryDelay != nil {
+			f.RetryAfter = info.RetryDelay.AsDuration()
+		}
+	}
+	return f
+}
+
+// parseRetryAfter parses the delay seconds or the HTTP date of the Retry-After header.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(time.Now()) {
+		return time.Until(t)
+	}
+	return 0
+}
+
 func doGRPC(wrapper Wrapper, body []byte, fn func(ctx context.Context, body []byte) error) error {
 	body, headers, err := wrapper.Unwrap(body)
 	if err != nil {
@@ -43,7 +218,8 @@ func doGRPC(wrapper Wrapper, body []byte, fn func(ctx context.Context, body []by
 	for k, v := range headers {
 		h[k] = string(v)
 	}
-	ctx, _ := context.WithDeadline(context.Background(), time.Now().Add(time.Second*60))
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(time.Second*60))
+	defer cancel()
 	ctx = metadata.NewOutgoingContext(ctx, metadata.New(h))
 	return fn(ctx, body)
 }
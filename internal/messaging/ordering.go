@@ -0,0 +1,132 @@
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/config"
+)
+
+// Orderer is implemented by the messagers that can hand the deliveries of the same key to the callback in order.
+type Orderer interface {
+	// CheckOrdering returns an error if the ordering settings can not be served by the messager.
+	CheckOrdering(opts ConsumeOptions) error
+}
+
+// checkOrdering returns an error if the consumer is ordered but the messager can not keep the order.
+func checkOrdering(m Messager, opts ConsumeOptions) error {
+	if opts.Ordering == nil {
+		return nil
+	}
+	if (opts.Ordering.KeyHeader == "") == (opts.Ordering.KeyField == "") {
+		return fmt.Errorf("one of the key header and the key field of the ordering is required")
+	}
+	o, ok := m.(Orderer)
+	if !ok {
+		return fmt.Errorf("ordered delivery is not supported by the broker")
+	}
+	return o.CheckOrdering(opts)
+}
+
+// OrderedRetryWait is the longest time a delivery of an ordered consumer is retried in place. The delivery is not
+// acknowledged while it waits, so the wait must stay below the ack timeouts of the brokers, the AckWait of nats, the
+// ClaimIdle of redis and the consumer_timeout of rabbitmq. The delivery is redelivered behind the next ones of its key
+// otherwise.
+const OrderedRetryWait = time.Minute
+
+// inOrder retries the failed deliveries of an ordered consumer in place, so the next deliveries of the same key
+// wait for them instead of overtaking them on the retry queue. A delivery that fails for longer than the
+// OrderedRetryWait is dead lettered, see classify.
+func inOrder(opts ConsumeOptions, callback Callback) Callback {
+	if opts.Key == nil {
+		return callback
+	}
+	return func(d *Delivery) Result {
+		d.RetryBy = time.Now().Add(OrderedRetryWait)
+		for {
+			result := callback(d)
+			if result.Action != Retry {
+				return result
+			}
+			time.Sleep(result.Delay)
+			d = &Delivery{Body: d.Body, Attempt: d.Attempt + 1, QueuedAt: d.QueuedAt, Name: d.Name, RetryBy: d.RetryBy}
+		}
+	}
+}
+
+// orderingKey returns the function that reads the key of a message from the proxied header or the JSON body field.
+// Messages without the key share the empty key.
+func orderingKey(cfg *config.OrderingConfig, w Wrapper) func(message []byte) []byte {
+	path := strings.Split(cfg.KeyField, ".")
+	return func(message []byte) []byte {
+		body, headers, err := w.Unwrap(message)
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to read the ordering key of the message")
+			return nil
+		}
+		if cfg.KeyHeader != "" {
+			return headers[cfg.KeyHeader]
+		}
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err != nil {
+			return nil
+		}
+		for _, field := range path {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			v = m[field]
+		}
+		switch v := v.(type) {
+		case nil:
+			return nil
+		case string:
+			return []byte(v)
+		default:
+			// numbers and the other values keep their JSON form.
+			key, _ := json.Marshal(v)
+			return key
+		}
+	}
+}
+
+// Lanes runs the handlers of the deliveries. The handlers of the same key run one after another in the order
+// they are given, the handlers of different keys run in parallel.
+type Lanes struct {
+	lanes []chan func()
+}
+
+// NewLanes starts a goroutine per lane. Every lane buffers as many handlers as the capacity, Run waits once
+// the lane of the key is full.
+func NewLanes(n int, capacity int) *Lanes {
+	l := &Lanes{lanes: make([]chan func(), n)}
+	for i := range l.lanes {
+		lane := make(chan func(), capacity)
+		l.lanes[i] = lane
+		go func() {
+			for fn := range lane {
+				fn()
+			}
+		}()
+	}
+	return l
+}
+
+// Run hands the handler over to the lane of the key.
+func (l *Lanes) Run(key []byte, fn func()) {
+	h := fnv.New32a()
+	h.Write(key)
+	l.lanes[h.Sum32()%uint32(len(l.lanes))] <- fn
+}
+
+// Close stops the lanes once their buffered handlers return.
+func (l *Lanes) Close() {
+	for _, lane := range l.lanes {
+		close(lane)
+	}
+}
@@ -0,0 +1,171 @@
+package messaging
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/config"
+)
+
+// PipelineStepHeader is the queue of the step a job failed on, on the jobs of the failure queue.
+const PipelineStepHeader = "x-pipeline-step"
+
+// Pipelines finds the pipeline step of a queue.
+type Pipelines struct {
+	steps map[string]*PipelineStep
+}
+
+// PipelineStep is a queue of a pipeline with the queues its succeeded jobs go to.
+type PipelineStep struct {
+	pipeline       string
+	next           []string
+	completedEvent string
+	failureQueue   string
+}
+
+// NewPipelines validates the pipelines. A queue can be a step of one pipeline only and the steps can not loop.
+func NewPipelines(cfgs []*config.PipelineConfig) (*Pipelines, error) {
+	p := &Pipelines{steps: map[string]*PipelineStep{}}
+	for _, cfg := range cfgs {
+		if cfg.Name == "" || len(cfg.Steps) == 0 {
+			return nil, fmt.Errorf("the name and the steps of the pipeline are required")
+		}
+		steps := map[string]*config.PipelineStepConfig{}
+		for _, s := range cfg.Steps {
+			if s.Queue == "" {
+				return nil, fmt.Errorf("the queue of a step of the pipeline %s is required", cfg.Name)
+			}
+			if _, ok := p.steps[s.Queue]; ok {
+				return nil, fmt.Errorf("the queue %s is used by more than one pipeline step", s.Queue)
+			}
+			steps[s.Queue] = s
+			p.steps[s.Queue] = &PipelineStep{
+				pipeline:       cfg.Name,
+				next:           s.Next,
+				completedEvent: cfg.CompletedEvent,
+				failureQueue:   cfg.FailureQueue,
+			}
+		}
+		if _, ok := steps[cfg.FailureQueue]; ok {
+			return nil, fmt.Errorf("the failure queue of the pipeline %s can not be one of its steps", cfg.Name)
+		}
+		for _, s := range cfg.Steps {
+			for _, next := range s.Next {
+				if _, ok := steps[next]; !ok {
+					return nil, fmt.Errorf("the next step %s of the pipeline %s is not one of its steps", next, cfg.Name)
+				}
+			}
+		}
+		if err := checkLoop(cfg.Name, steps); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// checkLoop returns an error if a job can come back to a step it passed.
+func checkLoop(pipeline string, steps map[string]*config.PipelineStepConfig) error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var visit func(queue string) error
+	visit = func(queue string) error {
+		switch state[queue] {
+		case visiting:
+			return fmt.Errorf("the steps of the pipeline %s loop on the queue %s", pipeline, queue)
+		case visited:
+			return nil
+		}
+		state[queue] = visiting
+		for _, next := range steps[queue].Next {
+			if err := visit(next); err != nil {
+				return err
+			}
+		}
+		state[queue] = visited
+		return nil
+	}
+	for queue := range steps {
+		if err := visit(queue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Step returns the pipeline step of the queue. nil if the queue is not a step.
+func (p *Pipelines) Step(queue string) *PipelineStep {
+	if p == nil {
+		return nil
+	}
+	return p.steps[queue]
+}
+
+// advance queues the response of the worker to the next steps, or publishes it as the completed event after the
+// last step. A job that fails for good is queued to the failure queue. An error is returned if the response of the
+// succeeded job is not queued to every next step, so the job is retried instead of the pipeline losing it. A nil
+// step does nothing.
+func (s *PipelineStep) advance(m Messager, w Wrapper, service string, name string, d *Delivery, r *Reply, result Result) error {
+	if s == nil || result.Action == Retry {
+		return nil
+	}
+	body, headers, err := w.Unwrap(d.Body)
+	if err != nil {
+		return err
+	}
+	h := make(map[string][]byte, len(headers)+2)
+	for k, v := range headers {
+		switch k {
+		case JobIDHeader, CallbackURLHeader, CallbackEventHeader, ReplyToHeader, CorrelationIDHeader:
+		default:
+			h[k] = v
+		}
+	}
+
+	if f := result.Failure; f != nil {
+		if s.failureQueue == "" {
+			return nil
+		}
+		h[PipelineStepHeader] = []byte(name)
+		h[ReplyStatusCodeHeader] = []byte(strconv.Itoa(f.StatusCode))
+		h[ReplyErrorHeader] = []byte(f.Error)
+		message, err := w.Wrap(body, h)
+		if err == nil {
+			err = m.Queue(service, s.failureQueue, message)
+		}
+		if err != nil {
+			log.Error().Err(err).Str("body", string(d.Body)).Str("service", service).Str("name", name).
+				Msgf("failed to queue the failed job to the failure queue %s of the pipeline %s", s.failureQueue, s.pipeline)
+		}
+		return nil
+	}
+
+	// a worker that responds with no body passes its job along.
+	if r != nil && len(r.Body) > 0 {
+		body = r.Body
+	}
+	message, err := w.Wrap(body, h)
+	if err != nil {
+		return err
+	}
+	if len(s.next) == 0 {
+		if s.completedEvent == "" {
+			return nil
+		}
+		if err := m.Publish(service, s.completedEvent, message); err == ErrUnroutable {
+			log.Warn().Str("name", s.completedEvent).Msg("published to no subscribers")
+		} else if err != nil {
+			return fmt.Errorf("failed to publish the completed event %s of the pipeline %s. %v", s.completedEvent, s.pipeline, err)
+		}
+		return nil
+	}
+	for _, next := range s.next {
+		if err := m.Queue(service, next, message); err != nil {
+			return fmt.Errorf("failed to queue the job to the next step %s of the pipeline %s. %v", next, s.pipeline, err)
+		}
+	}
+	return nil
+}
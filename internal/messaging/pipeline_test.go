@@ -0,0 +1,193 @@
+package messaging
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/turgayozgur/messageman/config"
+)
+
+func step(queue string, next ...string) *config.PipelineStepConfig {
+	return &config.PipelineStepConfig{Queue: queue, Next: next}
+}
+
+func TestNewPipelinesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfgs []*config.PipelineConfig
+		err  string
+	}{
+		{"no name", []*config.PipelineConfig{{Steps: []*config.PipelineStepConfig{step("a")}}}, "the name and the steps"},
+		{"no steps", []*config.PipelineConfig{{Name: "p"}}, "the name and the steps"},
+		{"no queue", []*config.PipelineConfig{{Name: "p", Steps: []*config.PipelineStepConfig{step("")}}}, "the queue of a step"},
+		{"same step twice", []*config.PipelineConfig{{Name: "p", Steps: []*config.PipelineStepConfig{step("a"), step("a")}}}, "more than one pipeline step"},
+		{"step of two pipelines", []*config.PipelineConfig{
+			{Name: "p", Steps: []*config.PipelineStepConfig{step("a")}},
+			{Name: "r", Steps: []*config.PipelineStepConfig{step("b", "a"), step("a")}},
+		}, "more than one pipeline step"},
+		{"failure queue is a step", []*config.PipelineConfig{{Name: "p", FailureQueue: "b", Steps: []*config.PipelineStepConfig{step("a", "b"), step("b")}}}, "can not be one of its steps"},
+		{"next is not a step", []*config.PipelineConfig{{Name: "p", Steps: []*config.PipelineStepConfig{step("a", "b")}}}, "is not one of its steps"},
+		{"next of another pipeline", []*config.PipelineConfig{
+			{Name: "p", Steps: []*config.PipelineStepConfig{step("a")}},
+			{Name: "r", Steps: []*config.PipelineStepConfig{step("b", "a")}},
+		}, "is not one of its steps"},
+		{"self loop", []*config.PipelineConfig{{Name: "p", Steps: []*config.PipelineStepConfig{step("a", "a")}}}, "loop on the queue a"},
+		{"loop", []*config.PipelineConfig{{Name: "p", Steps: []*config.PipelineStepConfig{step("a", "b"), step("b", "c"), step("c", "a")}}}, "loop on the queue"},
+		{"loop behind a fan out", []*config.PipelineConfig{{Name: "p", Steps: []*config.PipelineStepConfig{step("a", "b", "c"), step("b"), step("c", "d"), step("d", "c")}}}, "loop on the queue"},
+	}
+	for _, tt := range tests {
+		_, err := NewPipelines(tt.cfgs)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestNewPipelines(t *testing.T) {
+	p, err := NewPipelines([]*config.PipelineConfig{
+		// the steps fan out and join without a loop.
+		{Name: "media", FailureQueue: "media_failed", CompletedEvent: "media_ready", Steps: []*config.PipelineStepConfig{
+			step("upload", "transcode", "thumbnail"), step("transcode", "publish"), step("thumbnail", "publish"), step("publish"),
+		}},
+		{Name: "report", Steps: []*config.PipelineStepConfig{step("export")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := p.Step("upload")
+	if s == nil || s.pipeline != "media" || len(s.next) != 2 || s.failureQueue != "media_failed" || s.completedEvent != "media_ready" {
+		t.Errorf("unexpected step %+v", s)
+	}
+	if s := p.Step("export"); s == nil || s.pipeline != "report" {
+		t.Errorf("unexpected step %+v", s)
+	}
+	if s := p.Step("media_failed"); s != nil {
+		t.Errorf("the failure queue is a step %+v", s)
+	}
+	var none *Pipelines
+	if s := none.Step("upload"); s != nil {
+		t.Errorf("a step without pipelines %+v", s)
+	}
+}
+
+func TestPipelineAdvance(t *testing.T) {
+	w := &DefaultWrapper{}
+	p, err := NewPipelines([]*config.PipelineConfig{
+		{Name: "media", FailureQueue: "media_failed", CompletedEvent: "media_ready", Steps: []*config.PipelineStepConfig{
+			step("upload", "transcode", "thumbnail"), step("transcode"), step("thumbnail"),
+		}},
+		{Name: "report", Steps: []*config.PipelineStepConfig{step("export")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := w.Wrap([]byte(`{"file":"a.mov"}`), map[string][]byte{
+		"x-tenant":          []byte("acme"),
+		JobIDHeader:         []byte("42"),
+		CallbackURLHeader:   []byte("http://reportapi/done"),
+		CallbackEventHeader: []byte("done"),
+		ReplyToHeader:       []byte("messageman.reply.host-8015"),
+		CorrelationIDHeader: []byte("7"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Delivery{Body: message, Attempt: 1}
+	failure := &Failure{StatusCode: 422, Error: "bad file"}
+	tests := []struct {
+		name   string
+		queue  string
+		reply  *Reply
+		result Result
+		sent   []string // the names the job is sent to.
+		pubSub bool
+		body   string
+	}{
+		{"fan out", "upload", &Reply{Body: []byte(`{"id":1}`)}, Result{Action: Ack}, []string{"transcode", "thumbnail"}, false, `{"id":1}`},
+		{"no response body", "upload", &Reply{}, Result{Action: Ack}, []string{"transcode", "thumbnail"}, false, `{"file":"a.mov"}`},
+		{"completed", "transcode", &Reply{Body: []byte(`{"url":"x"}`)}, Result{Action: Ack}, []string{"media_ready"}, true, `{"url":"x"}`},
+		{"completed without an event", "export", &Reply{Body: []byte(`{}`)}, Result{Action: Ack}, nil, false, ""},
+		{"retried", "upload", nil, Result{Action: Retry, Failure: failure}, nil, false, ""},
+		{"failed", "upload", nil, Result{Action: Dead, Failure: failure}, []string{"media_failed"}, false, `{"file":"a.mov"}`},
+		{"dropped", "transcode", nil, Result{Action: Ack, Failure: failure}, []string{"media_failed"}, false, `{"file":"a.mov"}`},
+		{"failed without a failure queue", "export", nil, Result{Action: Dead, Failure: failure}, nil, false, ""},
+		{"not a step", "other", &Reply{Body: []byte(`{}`)}, Result{Action: Ack}, nil, false, ""},
+	}
+	for _, tt := range tests {
+		m := &recorder{}
+		if err := p.Step(tt.queue).advance(m, w, "api", tt.queue, d, tt.reply, tt.result); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(m.sent) != len(tt.sent) {
+			t.Errorf("%s: sent %d jobs, want %v", tt.name, len(m.sent), tt.sent)
+			continue
+		}
+		for n, s := range m.sent {
+			if s.name != tt.sent[n] || s.pubSub != tt.pubSub || s.service != "api" {
+				t.Errorf("%s: sent to %s, pub/sub %v, want %s", tt.name, s.name, s.pubSub, tt.sent[n])
+			}
+			body, headers, err := w.Unwrap(s.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.body {
+				t.Errorf("%s: the body is %s, want %s", tt.name, body, tt.body)
+			}
+			if string(headers["x-tenant"]) != "acme" {
+				t.Errorf("%s: the proxied header is lost", tt.name)
+			}
+			// the next job is a job of its own.
+			for _, h := range []string{JobIDHeader, CallbackURLHeader, CallbackEventHeader, ReplyToHeader, CorrelationIDHeader} {
+				if _, ok := headers[h]; ok {
+					t.Errorf("%s: the %s header is passed along", tt.name, h)
+				}
+			}
+			if tt.result.Failure != nil {
+				if string(headers[PipelineStepHeader]) != tt.queue || string(headers[ReplyStatusCodeHeader]) != "422" || string(headers[ReplyErrorHeader]) != "bad file" {
+					t.Errorf("%s: the failure is not on the headers %v", tt.name, headers)
+				}
+			}
+		}
+	}
+}
+
+func TestPipelineAdvanceErrors(t *testing.T) {
+	w := &DefaultWrapper{}
+	p, err := NewPipelines([]*config.PipelineConfig{
+		{Name: "media", FailureQueue: "media_failed", CompletedEvent: "media_ready", Steps: []*config.PipelineStepConfig{
+			step("upload", "transcode"), step("transcode"),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := w.Wrap([]byte(`{}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Delivery{Body: message, Attempt: 1}
+
+	// the succeeded job is retried if the next step does not get it.
+	m := &recorder{err: errors.New("connection lost")}
+	if err := p.Step("upload").advance(m, w, "api", "upload", d, &Reply{}, Result{Action: Ack}); err == nil {
+		t.Error("expected an error on a next step that is not queued")
+	}
+	if err := p.Step("transcode").advance(m, w, "api", "transcode", d, &Reply{}, Result{Action: Ack}); err == nil {
+		t.Error("expected an error on a completed event that is not published")
+	}
+	// a failure never fails to advance.
+	if err := p.Step("upload").advance(m, w, "api", "upload", d, nil, Result{Action: Dead, Failure: &Failure{}}); err != nil {
+		t.Errorf("a failure failed to advance. %v", err)
+	}
+	// a completed event without subscribers is not an error.
+	m = &recorder{err: ErrUnroutable}
+	if err := p.Step("transcode").advance(m, w, "api", "transcode", d, &Reply{}, Result{Action: Ack}); err != nil {
+		t.Errorf("an unroutable completed event is an error. %v", err)
+	}
+	// a message that can not be unwrapped is not passed along.
+	if err := p.Step("upload").advance(&recorder{}, w, "api", "upload", &Delivery{Body: []byte("not wrapped")}, &Reply{}, Result{Action: Ack}); err == nil {
+		t.Error("expected an error on a message that can not be unwrapped")
+	}
+}
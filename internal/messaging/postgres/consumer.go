@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+// consume starts a claimer per concurrency, every claimer handles one row at a time.
+func (p *Postgres) consume(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions, pubSub bool) error {
+	db, err := p.connection()
+	if err != nil {
+		return err
+	}
+	queueName := p.getQueueName(service, name, pubSub)
+	_, err = db.Exec(
+		`INSERT INTO messageman_bindings (name, queue) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		name, queueName)
+	if err != nil {
+		return fmt.Errorf("failed to bind queue. %v", err)
+	}
+
+	for i := 0; i < opts.Concurrency; i++ {
+		go func() {
+			for {
+				ok, err := p.next(db, service, name, queueName, callback)
+				if err != nil {
+					p.exporter.IncError(service)
+					log.Error().Str("service", service).Str("name", name).Msgf("failed to claim a message. %v", err)
+					time.Sleep(WaitToReconnectDuration)
+					continue
+				}
+				if !ok {
+					time.Sleep(PollInterval)
+				}
+			}
+		}()
+	}
+
+	return nil
+}
+
+// next claims the oldest due row of the queue and keeps it locked until the callback returns.
+// Rows of a crashed consumer are unlocked by the server when its connection drops.
+func (p *Postgres) next(db *sql.DB, service string, name string, queueName string, callback messaging.Callback) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	d := &messaging.Delivery{}
+	err = tx.QueryRow(
+		`SELECT id, body, attempt, created_at FROM messageman_messages
+		WHERE queue = $1 AND next_attempt_at <= now()
+		ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED`,
+		queueName).Scan(&id, &d.Body, &d.Attempt, &d.QueuedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	start := time.Now()
+	result := p.invokeConsumerFunc(d, callback)
+	switch result.Action {
+	case messaging.Retry:
+		_, err = tx.Exec(
+			`UPDATE messageman_messages SET attempt = attempt + 1, next_attempt_at = now() + $2 * interval '1 millisecond' WHERE id = $1`,
+			id, result.Delay.Milliseconds())
+		p.exporter.IncConsumeError(service, name)
+	case messaging.Dead:
+		err = p.deadLetter(tx, id, messaging.NewDeadLetter(service, name, queueName, d, result.Failure))
+		p.exporter.IncConsumeError(service, name)
+	default:
+		_, err = tx.Exec(`DELETE FROM messageman_messages WHERE id = $1`, id)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Error().Msgf("failed to send ACK. %v", err)
+	}
+	p.exporter.ConsumeSeconds(time.Since(start), service, name)
+	return true, nil
+}
+
+func (p *Postgres) invokeConsumerFunc(d *messaging.Delivery, callback messaging.Callback) (result messaging.Result) {
+	defer func() {
+		if rc := recover(); rc != nil {
+			log.Error().Msgf("error when invoking the consumer function: %+v", rc)
+			result = messaging.Result{Action: messaging.Retry, Delay: RetryDelay}
+		}
+	}()
+	if callback == nil {
+		log.Warn().Msgf("callback function is nil. Make sure you have correct setup for your consumer")
+		return messaging.Result{Action: messaging.Retry, Delay: RetryDelay}
+	}
+	return callback(d)
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+// deadLetter moves the claimed row to the messageman_dead_letters table on the claiming transaction.
+func (p *Postgres) deadLetter(tx *sql.Tx, id int64, l *messaging.DeadLetter) error {
+	_, err := tx.Exec(
+		`INSERT INTO messageman_dead_letters (queue, name, service, body, attempt, status_code, error, queued_at, failed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		l.Queue, l.Name, l.Service, l.Body, l.Attempt, l.StatusCode, l.Error, l.QueuedAt, l.FailedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM messageman_messages WHERE id = $1`, id)
+	return err
+}
+
+// DeadLetterQueues .
+func (p *Postgres) DeadLetterQueues() ([]*messaging.DeadLetterQueue, error) {
+	db, err := p.connection()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT queue, count(*) FROM messageman_dead_letters GROUP BY queue ORDER BY queue`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var queues []*messaging.DeadLetterQueue
+	for rows.Next() {
+		q := &messaging.DeadLetterQueue{}
+		if err := rows.Scan(&q.Queue, &q.Count); err != nil {
+			return nil, err
+		}
+		queues = append(queues, q)
+	}
+	return queues, rows.Err()
+}
+
+// PeekDeadLetters returns the oldest dead letters of the queue without removing them.
+func (p *Postgres) PeekDeadLetters(queue string, limit int) ([]*messaging.DeadLetter, error) {
+	db, err := p.connection()
+	if err != nil {
+		return nil, err
+	}
+	var l sql.NullInt64
+	if limit > 0 {
+		l = sql.NullInt64{Int64: int64(limit), Valid: true}
+	}
+	rows, err := db.Query(
+		`SELECT id, queue, name, service, body, attempt, status_code, error, queued_at, failed_at
+		FROM messageman_dead_letters WHERE queue = $1 ORDER BY id LIMIT $2`,
+		queue, l)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var letters []*messaging.DeadLetter
+	for rows.Next() {
+		var id int64
+		d := &messaging.DeadLetter{}
+		err := rows.Scan(&id, &d.Queue, &d.Name, &d.Service, &d.Body, &d.Attempt, &d.StatusCode, &d.Error, &d.QueuedAt, &d.FailedAt)
+		if err != nil {
+			return nil, err
+		}
+		d.ID = strconv.FormatInt(id, 10)
+		letters = append(letters, d)
+	}
+	return letters, rows.Err()
+}
+
+// ReplayDeadLetter moves the dead letter back to the messageman_messages table in one transaction.
+func (p *Postgres) ReplayDeadLetter(queue string, id string, message []byte) error {
+	key, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return messaging.ErrDeadLetterNotFound
+	}
+	db, err := p.connection()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var body []byte
+	err = tx.QueryRow(`DELETE FROM messageman_dead_letters WHERE id = $1 AND queue = $2 RETURNING body`, key, queue).Scan(&body)
+	if err == sql.ErrNoRows {
+		return messaging.ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if message == nil {
+		message = body
+	}
+	if _, err := tx.Exec(`INSERT INTO messageman_messages (queue, body) VALUES ($1, $2)`, queue, message); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PurgeDeadLetters .
+func (p *Postgres) PurgeDeadLetters(queue string) (int64, error) {
+	db, err := p.connection()
+	if err != nil {
+		return 0, err
+	}
+	result, err := db.Exec(`DELETE FROM messageman_dead_letters WHERE queue = $1`, queue)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
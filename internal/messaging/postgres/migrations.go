@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// migrationLockID is the advisory lock that keeps the replicas from migrating at the same time.
+const migrationLockID = 7206348317
+
+// migrations are applied in order and recorded on the messageman_schema_migrations table.
+// Never change a released migration, append a new one.
+var migrations = []string{
+	// 1: bindings of the consumer queues to the queue and event names.
+	`CREATE TABLE messageman_bindings (
+		name  TEXT NOT NULL,
+		queue TEXT NOT NULL,
+		PRIMARY KEY (name, queue)
+	)`,
+	// 2: deliveries waiting for the consumers.
+	`CREATE TABLE messageman_messages (
+		id              BIGSERIAL PRIMARY KEY,
+		queue           TEXT NOT NULL,
+		body            BYTEA NOT NULL,
+		attempt         INT NOT NULL DEFAULT 1,
+		created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+		next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+	CREATE INDEX messageman_messages_queue_next_attempt_at_idx ON messageman_messages (queue, next_attempt_at, id)`,
+	// 3: messages that used up their attempts, with the reason of the last failure.
+	`CREATE TABLE messageman_dead_letters (
+		id          BIGSERIAL PRIMARY KEY,
+		queue       TEXT NOT NULL,
+		name        TEXT NOT NULL,
+		service     TEXT NOT NULL,
+		body        BYTEA NOT NULL,
+		attempt     INT NOT NULL,
+		status_code INT NOT NULL,
+		error       TEXT NOT NULL,
+		queued_at   TIMESTAMPTZ NOT NULL,
+		failed_at   TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX messageman_dead_letters_queue_idx ON messageman_dead_letters (queue, id)`,
+	// 4: ticks of the schedules, claimed by a single replica.
+	`CREATE TABLE messageman_schedule_ticks (
+		schedule TEXT NOT NULL,
+		tick     TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (schedule, tick)
+	)`,
+}
+
+func migrate(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to migrate the schema. %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to migrate the schema. %v", err)
+	}
+	_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS messageman_schema_migrations (
+		version    INT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to migrate the schema. %v", err)
+	}
+	var version int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM messageman_schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to migrate the schema. %v", err)
+	}
+	for i := version; i < len(migrations); i++ {
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			return fmt.Errorf("failed to apply the migration %d. %v", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO messageman_schema_migrations (version) VALUES ($1)`, i+1); err != nil {
+			return fmt.Errorf("failed to apply the migration %d. %v", i+1, err)
+		}
+		log.Info().Msgf("schema migration %d applied", i+1)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	// registers the postgres driver.
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/messaging"
+	"github.com/turgayozgur/messageman/internal/metrics"
+)
+
+const (
+	// RetryDelay constant. Matches the TTL of the RabbitMQ retry queue. Used when the callback fails unexpectedly.
+	RetryDelay = 30 * time.Second
+	// PollInterval constant. How often an idle consumer looks for new rows.
+	PollInterval = 500 * time.Millisecond
+	// TickRetention constant. How long the claimed ticks of the schedules are kept.
+	TickRetention = 24 * time.Hour
+	// WaitToReconnectDuration constant.
+	WaitToReconnectDuration = 5 * time.Second
+	// ConnectionName constant.
+	ConnectionName = "postgres"
+)
+
+// Postgres messager. Every delivery is a row on the messageman_messages table, claimed by
+// the consumers with SELECT ... FOR UPDATE SKIP LOCKED.
+type Postgres struct {
+	exporter metrics.Exporter
+	recover  chan string
+	mu       sync.Mutex
+	db       *sql.DB
+}
+
+// New ctor
+func New(exporter metrics.Exporter) *Postgres {
+	return &Postgres{exporter: exporter}
+}
+
+// EnsureCanConnect to ensure connection is available to the database
+func (p *Postgres) EnsureCanConnect() bool {
+	db, err := sql.Open("postgres", config.Cfg.Postgres.Url)
+	if err != nil {
+		log.Error().Msgf("failed to open the PostgreSQL database. %v", err)
+		return false
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		log.Error().Msgf("failed to connect to the PostgreSQL server. error:%v", err)
+		return false
+	}
+	log.Info().Msg("successfully connected to the PostgreSQL server")
+	return true
+}
+
+// NotifyRecover .
+func (p *Postgres) NotifyRecover(ch chan string) chan string {
+	p.recover = ch
+	return p.recover
+}
+
+// Queue .
+func (p *Postgres) Queue(service string, name string, message []byte) error {
+	return p.QueueAt(service, name, message, time.Time{})
+}
+
+// QueueAt .
+func (p *Postgres) QueueAt(service string, name string, message []byte, at time.Time) (err error) {
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			log.Error().Err(err).Msg("could not be queued")
+			p.exporter.IncSendError(service, name)
+		}
+		p.exporter.SendSeconds(time.Since(start), service, name)
+	}()
+	if err := p.send(name, message, at); err != nil {
+		return err
+	}
+	log.Debug().Str("name", name).Msgf("queued")
+	return nil
+}
+
+// Work .
+func (p *Postgres) Work(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) error {
+	if err := p.consume(service, name, callback, opts, false); err != nil {
+		return err
+	}
+	p.exporter.IncConsumer(service, name)
+	return nil
+}
+
+// Publish .
+func (p *Postgres) Publish(service string, name string, message []byte) error {
+	return p.PublishAt(service, name, message, time.Time{})
+}
+
+// PublishAt .
+func (p *Postgres) PublishAt(service string, name string, message []byte, at time.Time) (err error) {
+	start := time.Now()
+	defer func() {
+		if err == messaging.ErrUnroutable {
+			log.Warn().Str("name", name).Msg("published to no subscribers")
+		} else if err != nil {
+			log.Error().Err(err).Msg("could not be published")
+			p.exporter.IncSendError(service, name)
+		}
+		p.exporter.SendSeconds(time.Since(start), service, name)
+	}()
+	if err := p.send(name, message, at); err != nil {
+		return err
+	}
+	log.Debug().Str("name", name).Msgf("published")
+	return nil
+}
+
+// Subscribe .
+func (p *Postgres) Subscribe(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) error {
+	if err := p.consume(service, name, callback, opts, true); err != nil {
+		return err
+	}
+	p.exporter.IncConsumer(service, name)
+	return nil
+}
+
+// send writes one row per queue bound to the name, claimable from the given time. Like a RabbitMQ
+// exchange without bindings, the message is unroutable if no worker or subscriber registered yet.
+func (p *Postgres) send(name string, message []byte, at time.Time) error {
+	db, err := p.connection()
+	if err != nil {
+		return err
+	}
+	result, err := db.Exec(
+		`INSERT INTO messageman_messages (queue, body, next_attempt_at)
+		SELECT queue, $2, GREATEST($3, now()) FROM messageman_bindings WHERE name = $1`,
+		name, message, at)
+	if err != nil {
+		return fmt.Errorf("failed to publish a message. %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return messaging.ErrUnroutable
+	}
+	return nil
+}
+
+// connection returns the shared connection pool and migrates the schema on the first call.
+func (p *Postgres) connection() (*sql.DB, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.db != nil {
+		return p.db, nil
+	}
+	db, err := sql.Open("postgres", config.Cfg.Postgres.Url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the PostgreSQL database. %v", err)
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	p.db = db
+	p.exporter.IncConnection(ConnectionName)
+	return p.db, nil
+}
+
+func (p *Postgres) getQueueName(service string, name string, pubSub bool) string {
+	var queueName string
+	if pubSub {
+		queueName = fmt.Sprintf("%s.%s", name, service)
+	} else {
+		queueName = name
+	}
+	return queueName
+}
@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"time"
+)
+
+// LockTick inserts the tick of the schedule, only one replica succeeds. The old ticks of the schedule are removed.
+func (p *Postgres) LockTick(schedule string, tick time.Time) (bool, error) {
+	db, err := p.connection()
+	if err != nil {
+		return false, err
+	}
+	result, err := db.Exec(`INSERT INTO messageman_schedule_ticks (schedule, tick) VALUES ($1, $2) ON CONFLICT DO NOTHING`, schedule, tick)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, nil
+	}
+	_, err = db.Exec(`DELETE FROM messageman_schedule_ticks WHERE schedule = $1 AND tick < $2`, schedule, tick.Add(-TickRetention))
+	return true, err
+}
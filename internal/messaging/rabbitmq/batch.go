@@ -0,0 +1,118 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/streadway/amqp"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+// QueueBatch .
+func (r *RabbitMQ) QueueBatch(service string, messages []*messaging.BatchMessage) []error {
+	return r.sendBatch(service, messages, false)
+}
+
+// PublishBatch .
+func (r *RabbitMQ) PublishBatch(service string, messages []*messaging.BatchMessage) []error {
+	return r.sendBatch(service, messages, true)
+}
+
+// sendBatch publishes the messages as mandatory over a single channel in confirm mode. A message is sent once the
+// broker confirms it, the confirmations come in the order of the publishing. The returned messages are told apart
+// by their message id, the index of the message on the batch.
+func (r *RabbitMQ) sendBatch(service string, messages []*messaging.BatchMessage, pubSub bool) []error {
+	verb := "queued"
+	if pubSub {
+		verb = "published"
+	}
+	start := time.Now()
+	errs := make([]error, len(messages))
+	fail := func(err error) []error {
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = err
+			}
+		}
+		return errs
+	}
+	defer func() {
+		for i, m := range messages {
+			if errs[i] == messaging.ErrUnroutable {
+				log.Warn().Str("name", m.Name).Msgf("%s to no consumers", verb)
+			} else if errs[i] != nil {
+				log.Error().Err(errs[i]).Str("name", m.Name).Msgf("could not be %s", verb)
+				r.exporter.IncSendError(service, m.Name)
+			}
+		}
+		log.Debug().Int("count", len(messages)).Msgf("batch %s", verb)
+	}()
+
+	channel, err := r.adminChannel()
+	if err != nil {
+		return fail(err)
+	}
+	defer r.closeAdminChannel(channel)
+	if err := channel.Confirm(false); err != nil {
+		return fail(fmt.Errorf("failed to put the channel in confirm mode. %v", err))
+	}
+	confirms := channel.NotifyPublish(make(chan amqp.Confirmation, len(messages)))
+	returns := channel.NotifyReturn(make(chan amqp.Return, len(messages)))
+
+	exchanges := map[string]error{}
+	var published []int
+	for i, m := range messages {
+		err, ok := exchanges[m.Name]
+		if !ok {
+			err = r.exchange(channel, m.Name)
+			if err == nil && pubSub {
+				err = r.route(channel, m.Name)
+			}
+			exchanges[m.Name] = err
+		}
+		if err == nil {
+			var headers amqp.Table
+			if pubSub {
+				headers = amqp.Table{EventHeader: m.Name}
+			}
+			p := publishing(m.Message, headers, time.Now())
+			p.MessageId = strconv.Itoa(i)
+			err = r.publish(channel, m.Name, m.Name, true, p)
+		}
+		if err != nil {
+			// the channel is closed by the server on a failed declare, the rest can not be sent either. The
+			// published ones may still be confirmed.
+			for j := i; j < len(messages); j++ {
+				errs[j] = err
+			}
+			break
+		}
+		published = append(published, i)
+	}
+	for n, i := range published {
+		c, ok := <-confirms
+		if !ok {
+			for _, j := range published[n:] {
+				errs[j] = fmt.Errorf("the channel is closed before the confirmation")
+			}
+			break
+		}
+		if !c.Ack {
+			errs[i] = messaging.ErrNacked
+		}
+	}
+	// every return comes before its confirmation.
+	for len(returns) > 0 {
+		ret := <-returns
+		if i, err := strconv.Atoi(ret.MessageId); err == nil && i < len(errs) && errs[i] == nil {
+			errs[i] = messaging.ErrUnroutable
+		}
+	}
+	elapsed := time.Since(start)
+	for _, m := range messages {
+		r.exporter.SendSeconds(elapsed/time.Duration(len(messages)), service, m.Name)
+	}
+	return errs
+}
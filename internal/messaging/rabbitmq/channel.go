@@ -4,10 +4,11 @@ import (
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"github.com/streadway/amqp"
+	"github.com/turgayozgur/messageman/internal/messaging"
 	"time"
 )
 
-func (r *RabbitMQ) channel(service string, name string, callback func([]byte) bool) (channel *amqp.Channel, err error) {
+func (r *RabbitMQ) channel(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) (channel *amqp.Channel, err error) {
 	connection := r.connection(name)
 	channel, err = connection.Channel()
 	if err != nil {
@@ -31,7 +32,7 @@ func (r *RabbitMQ) channel(service string, name string, callback func([]byte) bo
 				ch, err := connection.Channel()
 				if err == nil {
 					channel = ch
-					if err := r.consume(channel, service, name, callback, false); err != nil {
+					if err := r.consume(channel, service, name, callback, opts, false); err != nil {
 						log.Error().Msgf("failed to recover consumer %s. %v", name, err)
 						continue
 					}
@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"github.com/streadway/amqp"
+	"github.com/turgayozgur/messageman/internal/messaging"
 	"time"
 )
 
-func (r *RabbitMQ) consume(channel *amqp.Channel, service string, name string, callback func([]byte) bool, pubSub bool) error {
-	if err := r.bind(channel, service, name, pubSub); err != nil {
+// consume starts the handlers of the consumer queue. Every handler acknowledges its own deliveries, the prefetch
+// count bounds the deliveries waiting for a free handler.
+func (r *RabbitMQ) consume(channel *amqp.Channel, service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions, pubSub bool) error {
+	if err := r.bind(channel, service, name, opts.Key != nil, pubSub); err != nil {
 		return err
 	}
 	if err := r.bindRetry(channel, service, name, pubSub); err != nil {
@@ -16,7 +19,13 @@ func (r *RabbitMQ) consume(channel *amqp.Channel, service string, name string, c
 	}
 
 	queueName := r.getQueueName(service, name, pubSub)
+	if err := r.bindDead(channel, queueName); err != nil {
+		return err
+	}
 
+	if err := channel.Qos(opts.PrefetchCount(), 0, false); err != nil {
+		return fmt.Errorf("failed to set the prefetch count. %v", err)
+	}
 	messages, err := channel.Consume(
 		queueName, // queue
 		"",        // consumer
@@ -30,34 +39,94 @@ func (r *RabbitMQ) consume(channel *amqp.Channel, service string, name string, c
 		return err
 	}
 
-	go func() {
-	loop:
-		for {
-			select {
-			case d := <-messages:
-				start := time.Now()
-				body := d.Body
-				success := r.invokeConsumerFunc(body, callback)
-				if !success {
-					if err := r.send(channel, r.getRetryExchangeName(name), queueName, body); err != nil {
-						log.Error().Msgf("failed to publish to retry queue. %v", err)
-					}
-					r.exporter.IncConsumeError(service, name)
-				}
-				if err := d.Ack(false); err != nil {
-					log.Error().Msgf("failed to send ACK. %v", err)
-				}
-				r.exporter.ConsumeSeconds(time.Since(start), service, name)
-			case <-channel.NotifyClose(make(chan *amqp.Error)):
-				log.Error().Str("service", service).Str("name", name).Msgf("consumer stopped. %v", err)
-				break loop
-			}
+	if opts.Key != nil {
+		go r.handleOrdered(channel, messages, service, name, queueName, callback, opts)
+		return nil
+	}
+	for i := 0; i < opts.Concurrency; i++ {
+		go r.handle(channel, messages, service, name, queueName, callback)
+	}
+	return nil
+}
+
+// deliver invokes the callback and acknowledges the delivery on its own, the other handlers do not wait for it.
+func (r *RabbitMQ) deliver(channel *amqp.Channel, d amqp.Delivery, service string, name string, queueName string, callback messaging.Callback) {
+	start := time.Now()
+	attempt := getAttempt(d.Headers)
+	if at, ok := getDeliverAt(d.Headers); ok && at.After(start) {
+		// a scheduled message that is not due yet waits on the retry queues of the consumer, it is not an attempt.
+		r.settle(d, r.retry(channel, name, queueName, d, attempt, parkLevel(at.Sub(start))))
+		return
+	}
+	delivery := &messaging.Delivery{Body: d.Body, Attempt: attempt, QueuedAt: d.Timestamp}
+	delivery.Name, _ = d.Headers[EventHeader].(string)
+	result := r.invokeConsumerFunc(delivery, callback)
+	var err error
+	switch result.Action {
+	case messaging.Retry:
+		if err = r.retry(channel, name, queueName, d, attempt+1, ttlLevel(result.Delay)); err != nil {
+			log.Error().Msgf("failed to publish to retry queue. %v", err)
 		}
-	}()
+		r.exporter.IncConsumeError(service, name)
+	case messaging.Dead:
+		if err = r.deadLetter(channel, d, messaging.NewDeadLetter(service, name, queueName, delivery, result.Failure)); err != nil {
+			log.Error().Msgf("failed to publish to dead letter queue. %v", err)
+		}
+		r.exporter.IncConsumeError(service, name)
+	}
+	r.settle(d, err)
+	r.exporter.ConsumeSeconds(time.Since(start), service, name)
+}
+
+// settle acknowledges the delivery if it is handed over without an error.
+func (r *RabbitMQ) settle(d amqp.Delivery, err error) {
+	if err != nil {
+		// requeued instead of acked, so the message is not lost.
+		if err := d.Nack(false, true); err != nil {
+			log.Error().Msgf("failed to send NACK. %v", err)
+		}
+	} else if err := d.Ack(false); err != nil {
+		log.Error().Msgf("failed to send ACK. %v", err)
+	}
+}
 
+// handleOrdered hands the deliveries over to the lanes of their keys. The queue has a single active consumer,
+// so the deliveries come in the order they are queued.
+func (r *RabbitMQ) handleOrdered(channel *amqp.Channel, messages <-chan amqp.Delivery, service string, name string, queueName string, callback messaging.Callback, opts messaging.ConsumeOptions) {
+	lanes := messaging.NewLanes(opts.Concurrency, opts.PrefetchCount())
+	defer lanes.Close()
+	for d := range messages {
+		d := d
+		lanes.Run(opts.Key(d.Body), func() {
+			r.deliver(channel, d, service, name, queueName, callback)
+		})
+	}
+	log.Error().Str("service", service).Str("name", name).Msg("consumer stopped. deliveries closed")
+}
+
+// CheckOrdering always succeeds, see bind.
+func (r *RabbitMQ) CheckOrdering(opts messaging.ConsumeOptions) error {
 	return nil
 }
 
+func (r *RabbitMQ) handle(channel *amqp.Channel, messages <-chan amqp.Delivery, service string, name string, queueName string, callback messaging.Callback) {
+	closed := channel.NotifyClose(make(chan *amqp.Error, 1))
+loop:
+	for {
+		select {
+		case d, ok := <-messages:
+			if !ok {
+				log.Error().Str("service", service).Str("name", name).Msg("consumer stopped. deliveries closed")
+				break loop
+			}
+			r.deliver(channel, d, service, name, queueName, callback)
+		case reason := <-closed:
+			log.Error().Str("service", service).Str("name", name).Msgf("consumer stopped. %v", reason)
+			break loop
+		}
+	}
+}
+
 func (r *RabbitMQ) exchange(channel *amqp.Channel, name string) error {
 	return channel.ExchangeDeclare(
 		name,     // name
@@ -70,7 +139,50 @@ func (r *RabbitMQ) exchange(channel *amqp.Channel, name string) error {
 	)
 }
 
-func (r *RabbitMQ) bind(channel *amqp.Channel, service string, name string, pubSub bool) error {
+// route binds the exchange of the event to the events exchange, so the event reaches the subscribers of the
+// matching patterns too. Only the messages routed with the event name pass, not the retries of the subscribers.
+func (r *RabbitMQ) route(channel *amqp.Channel, name string) error {
+	if err := r.eventsExchange(channel); err != nil {
+		return err
+	}
+	err := channel.ExchangeBind(
+		EventsExchangeName, // destination
+		name,               // routing key
+		name,               // source
+		false,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to bind exchange to events exchange. %v", err)
+	}
+	return nil
+}
+
+func (r *RabbitMQ) eventsExchange(channel *amqp.Channel) error {
+	err := channel.ExchangeDeclare(
+		EventsExchangeName, // name
+		"topic",            // type
+		true,               // durable
+		false,              // auto-deleted
+		false,              // internal
+		false,              // no-wait
+		nil,                // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare the events exchange. %v", err)
+	}
+	return nil
+}
+
+// CheckPattern always succeeds, see bind.
+func (r *RabbitMQ) CheckPattern(pattern string) error {
+	return nil
+}
+
+// bind declares the consumer queue. The queue of an ordered consumer delivers to a single active consumer,
+// the queue arguments can not be changed once the queue is declared. The queue of a pattern subscriber is bound to
+// the events exchange too, its own exchange only routes the retries and the replays.
+func (r *RabbitMQ) bind(channel *amqp.Channel, service string, name string, ordered bool, pubSub bool) error {
 	err := r.exchange(channel, name)
 	if err != nil {
 		return fmt.Errorf("failed to declare a main exchange. %v", err)
@@ -78,13 +190,17 @@ func (r *RabbitMQ) bind(channel *amqp.Channel, service string, name string, pubS
 
 	queueName := r.getQueueName(service, name, pubSub)
 
+	var args amqp.Table
+	if ordered {
+		args = amqp.Table{"x-single-active-consumer": true}
+	}
 	_, err = channel.QueueDeclare(
 		queueName, // name
 		true,      // durable
 		false,     // delete when unused
 		false,     // exclusive
 		false,     // no-wait
-		nil,       // arguments
+		args,      // arguments
 	)
 	if err != nil {
 		return fmt.Errorf("failed to declare a queue. %v", err)
@@ -112,6 +228,22 @@ func (r *RabbitMQ) bind(channel *amqp.Channel, service string, name string, pubS
 		return fmt.Errorf("failed to bind queue to exchange. %v", err)
 	}
 
+	if pubSub && messaging.IsPattern(name) {
+		if err := r.eventsExchange(channel); err != nil {
+			return err
+		}
+		err = channel.QueueBind(
+			queueName,          // queue name
+			name,               // pattern
+			EventsExchangeName, // exchange
+			false,
+			nil,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to bind queue to events exchange. %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -161,16 +293,78 @@ func (r *RabbitMQ) bindRetry(channel *amqp.Channel, service string, name string,
 	return nil
 }
 
-func (r *RabbitMQ) invokeConsumerFunc(body []byte, callback func([]byte) bool) (result bool) {
+// retry sends the message to the retry queue of the consumer queue. Messages are dead lettered
+// back to the consumer queue once the TTL of the retry queue expires. The default delay uses the
+// ".retry" queue, any other TTL level uses the ".retry.<ms>" queue of the level that is deleted when unused.
+func (r *RabbitMQ) retry(channel *amqp.Channel, name string, queueName string, d amqp.Delivery, attempt int, ttl int64) error {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[AttemptHeader] = int32(attempt)
+
+	if ttl == RetryQueueTTLMs {
+		return r.send(channel, r.getRetryExchangeName(name), queueName, d.Body, headers, d.Timestamp)
+	}
+
+	retryQueueName := fmt.Sprintf("%s.%s.%d", queueName, RetryQueueNameSuffix, ttl)
+	if err := r.ttlQueue(channel, retryQueueName, name, queueName, ttl); err != nil {
+		return fmt.Errorf("failed to declare a retry queue. %v", err)
+	}
+	return r.send(channel, "", retryQueueName, d.Body, headers, d.Timestamp)
+}
+
+// ttlQueue declares a queue that dead letters its messages to the exchange with the routing key once the TTL expires.
+// Declared before every send, so the queue never expires before its messages.
+func (r *RabbitMQ) ttlQueue(channel *amqp.Channel, queueName string, exchange string, route string, ttl int64) error {
+	_, err := channel.QueueDeclare(
+		queueName, // name
+		true,      // durable
+		false,     // delete when unused
+		false,     // exclusive
+		false,     // no-wait
+		amqp.Table{
+			"x-dead-letter-exchange":    exchange,
+			"x-dead-letter-routing-key": route,
+			"x-message-ttl":             ttl,
+			"x-expires":                 ttl + RetryQueueExpiresMs,
+		}, // arguments
+	)
+	return err
+}
+
+func (r *RabbitMQ) invokeConsumerFunc(d *messaging.Delivery, callback messaging.Callback) (result messaging.Result) {
 	defer func() {
 		if rc := recover(); rc != nil {
 			log.Error().Msgf("error when invoking the consumer function: %+v", rc)
-			result = false
+			result = messaging.Result{Action: messaging.Retry, Delay: RetryQueueTTLMs * time.Millisecond}
 		}
 	}()
 	if callback == nil {
 		log.Warn().Msgf("callback function is nil. Make sure you have correct setup for your consumer")
-		return false
+		return messaging.Result{Action: messaging.Retry, Delay: RetryQueueTTLMs * time.Millisecond}
+	}
+	return callback(d)
+}
+
+// getAttempt returns the delivery attempt of the message, starting from 1.
+func getAttempt(headers amqp.Table) int {
+	switch v := headers[AttemptHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 1
+}
+
+// getDeliverAt returns the time the scheduled message is due, false if it is due once it arrives.
+func getDeliverAt(headers amqp.Table) (time.Time, bool) {
+	ms, ok := headers[DeliverAtHeader].(int64)
+	if !ok {
+		return time.Time{}, false
 	}
-	return callback(body)
+	return time.Unix(0, ms*int64(time.Millisecond)), true
 }
@@ -0,0 +1,225 @@
+package rabbitmq
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/streadway/amqp"
+	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+// bindDead declares the dead letter queue of the consumer queue.
+func (r *RabbitMQ) bindDead(channel *amqp.Channel, queueName string) error {
+	_, err := channel.QueueDeclare(
+		messaging.GetDeadLetterQueueName(queueName), // name
+		true,  // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare a dead letter queue. %v", err)
+	}
+	r.mu.Lock()
+	r.queues[queueName] = true
+	r.mu.Unlock()
+	return nil
+}
+
+// deadLetter sends the message to the dead letter queue of the consumer queue. The failure travels on the headers.
+func (r *RabbitMQ) deadLetter(channel *amqp.Channel, d amqp.Delivery, l *messaging.DeadLetter) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[DeadLetterIDHeader] = hex.EncodeToString(id)
+	headers[ServiceHeader] = l.Service
+	headers[NameHeader] = l.Name
+	headers[AttemptHeader] = int32(l.Attempt)
+	headers[StatusCodeHeader] = int32(l.StatusCode)
+	headers[ErrorHeader] = l.Error
+	headers[FailedAtHeader] = l.FailedAt
+	return r.send(channel, "", messaging.GetDeadLetterQueueName(l.Queue), d.Body, headers, d.Timestamp)
+}
+
+// DeadLetterQueues returns the dead letter queues of the configured workers and subscribers and of the consumers
+// registered by this process. RabbitMQ can not list the queues over AMQP.
+func (r *RabbitMQ) DeadLetterQueues() ([]*messaging.DeadLetterQueue, error) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.queues))
+	for queueName := range r.queues {
+		names = append(names, queueName)
+	}
+	r.mu.Unlock()
+	for _, q := range config.Cfg.Queues {
+		names = append(names, r.getQueueName(q.Worker.Name, q.Name, false))
+	}
+	for _, e := range config.Cfg.Events {
+		for _, s := range e.Subscribers {
+			names = append(names, r.getQueueName(s.Name, e.Name, true))
+		}
+	}
+	sort.Strings(names)
+
+	channel, err := r.adminChannel()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { r.closeAdminChannel(channel) }()
+	var queues []*messaging.DeadLetterQueue
+	for i, queueName := range names {
+		if i > 0 && names[i-1] == queueName {
+			continue
+		}
+		q, err := channel.QueueInspect(messaging.GetDeadLetterQueueName(queueName))
+		if e, ok := err.(*amqp.Error); ok && e.Code == amqp.NotFound {
+			// no consumer declared the dead letter queue yet. The broker closes the channel on the error.
+			if channel, err = r.adminChannel(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if q.Messages > 0 {
+			queues = append(queues, &messaging.DeadLetterQueue{Queue: queueName, Count: int64(q.Messages)})
+		}
+	}
+	return queues, nil
+}
+
+// PeekDeadLetters gets the oldest dead letters without acknowledging them. They are requeued
+// in their order once the channel is closed.
+func (r *RabbitMQ) PeekDeadLetters(queue string, limit int) ([]*messaging.DeadLetter, error) {
+	channel, err := r.adminChannel()
+	if err != nil {
+		return nil, err
+	}
+	defer r.closeAdminChannel(channel)
+	var letters []*messaging.DeadLetter
+	for limit <= 0 || len(letters) < limit {
+		d, ok, err := channel.Get(messaging.GetDeadLetterQueueName(queue), false)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		letters = append(letters, readDeadLetter(queue, d))
+	}
+	return letters, nil
+}
+
+// ReplayDeadLetter sends the dead letter back to the main exchange with the routing key of its consumer queue. The
+// dead letters are got one by one until the ID is found, at most as many as the queue had when the replay started.
+// The skipped ones are requeued in their order. The replayed message keeps its headers, without the failure and the
+// attempt, so it starts over.
+func (r *RabbitMQ) ReplayDeadLetter(queue string, id string, message []byte) error {
+	channel, err := r.adminChannel()
+	if err != nil {
+		return err
+	}
+	defer r.closeAdminChannel(channel)
+	var skipped uint64
+	defer func() {
+		if skipped == 0 {
+			return
+		}
+		if err := channel.Nack(skipped, true, true); err != nil {
+			log.Error().Msgf("failed to requeue the skipped dead letters. %v", err)
+		}
+	}()
+	remaining := -1
+	for remaining != 0 {
+		d, ok, err := channel.Get(messaging.GetDeadLetterQueueName(queue), false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if remaining < 0 {
+			// the first message and the ones behind it.
+			remaining = int(d.MessageCount) + 1
+		}
+		remaining--
+		l := readDeadLetter(queue, d)
+		if l.ID != id {
+			skipped = d.DeliveryTag
+			continue
+		}
+		if message == nil {
+			message = l.Body
+		}
+		headers := amqp.Table{}
+		for k, v := range d.Headers {
+			headers[k] = v
+		}
+		for _, k := range []string{DeadLetterIDHeader, ServiceHeader, NameHeader, AttemptHeader, StatusCodeHeader, ErrorHeader, FailedAtHeader} {
+			delete(headers, k)
+		}
+		if err := r.exchange(channel, l.Name); err != nil {
+			return err
+		}
+		if err := r.send(channel, l.Name, queue, message, headers, time.Now()); err != nil {
+			return err
+		}
+		return d.Ack(false)
+	}
+	return messaging.ErrDeadLetterNotFound
+}
+
+// PurgeDeadLetters .
+func (r *RabbitMQ) PurgeDeadLetters(queue string) (int64, error) {
+	channel, err := r.adminChannel()
+	if err != nil {
+		return 0, err
+	}
+	defer r.closeAdminChannel(channel)
+	n, err := channel.QueuePurge(messaging.GetDeadLetterQueueName(queue), false)
+	return int64(n), err
+}
+
+// adminChannel opens a channel on the default connection. Unlike the consumer channels, it is not recovered once closed.
+func (r *RabbitMQ) adminChannel() (*amqp.Channel, error) {
+	channel, err := r.connection(DefaultConnectionName).Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open a channel. %v", err)
+	}
+	return channel, nil
+}
+
+func (r *RabbitMQ) closeAdminChannel(channel *amqp.Channel) {
+	if err := channel.Close(); err != nil && err != amqp.ErrClosed {
+		log.Error().Msgf("failed to close the channel. %v", err)
+	}
+}
+
+func readDeadLetter(queue string, d amqp.Delivery) *messaging.DeadLetter {
+	l := &messaging.DeadLetter{
+		Queue:    queue,
+		Body:     d.Body,
+		Attempt:  getAttempt(d.Headers),
+		QueuedAt: d.Timestamp,
+	}
+	l.ID, _ = d.Headers[DeadLetterIDHeader].(string)
+	l.Service, _ = d.Headers[ServiceHeader].(string)
+	l.Name, _ = d.Headers[NameHeader].(string)
+	l.Error, _ = d.Headers[ErrorHeader].(string)
+	l.FailedAt, _ = d.Headers[FailedAtHeader].(time.Time)
+	if v, ok := d.Headers[StatusCodeHeader].(int32); ok {
+		l.StatusCode = int(v)
+	}
+	return l
+}
@@ -4,19 +4,57 @@ import (
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"github.com/streadway/amqp"
+	"github.com/turgayozgur/messageman/internal/messaging"
 	"github.com/turgayozgur/messageman/internal/metrics"
+	"sync"
 	"time"
 )
 
 var (
 	connections map[string]*amqp.Connection
+	// ttlLevels are the TTLs of the retry and the delay queues in milliseconds. The delays are rounded to one of them,
+	// so a queue never gets more retry or delay queues than the levels.
+	ttlLevels = []int64{1000, 5000, 10000, RetryQueueTTLMs, 60000, 120000, 300000, 600000, 1800000, 3600000}
 )
 
 const (
 	// RetryQueueNameSuffix constant.
 	RetryQueueNameSuffix = "retry"
-	// RetryQueueTTLMs constant.
+	// RetryQueueTTLMs constant. The default retry delay.
 	RetryQueueTTLMs = 30 * 1000
+	// RetryQueueExpiresMs constant. How long an unused retry or delay queue outlives its TTL.
+	RetryQueueExpiresMs = 60 * 1000
+	// DelayQueueNameSuffix constant.
+	DelayQueueNameSuffix = "delay"
+	// DelayedExchangeNameSuffix constant. The exchange of the rabbitmq_delayed_message_exchange plugin.
+	DelayedExchangeNameSuffix = "delayed"
+	// DelayHeader constant. The delay in milliseconds read by the delayed message exchange.
+	DelayHeader = "x-delay"
+	// DeliverAtHeader constant. Unix milliseconds the scheduled message is due, set if it waits longer than a TTL level.
+	DeliverAtHeader = "x-deliver-at"
+	// EventsExchangeName constant. The topic exchange every event exchange is bound to, the subscribers of
+	// a pattern bind their queues to it.
+	EventsExchangeName = "messageman.events"
+	// EventHeader constant. The name the event is published with.
+	EventHeader = "x-event"
+	// AttemptHeader constant.
+	AttemptHeader = "x-attempt"
+	// DeadLetterIDHeader constant. Identifies the message on the dead letter queue.
+	DeadLetterIDHeader = "x-dead-letter-id"
+	// ServiceHeader constant.
+	ServiceHeader = "x-service"
+	// NameHeader constant. The exchange the dead letter is replayed to.
+	NameHeader = "x-name"
+	// StatusCodeHeader constant.
+	StatusCodeHeader = "x-status-code"
+	// ErrorHeader constant.
+	ErrorHeader = "x-error"
+	// FailedAtHeader constant.
+	FailedAtHeader = "x-failed-at"
+	// SchedulerQueueName constant. Exclusive queue held by the replica that fires the ticks of the schedules.
+	SchedulerQueueName = "messageman.scheduler"
+	// ConfirmTimeout constant. How long a publisher waits for the broker to confirm the message.
+	ConfirmTimeout = 10 * time.Second
 	// WaitToReconnectDuration constant.
 	WaitToReconnectDuration = 5 * time.Second
 	// DefaultConnectionName constant.
@@ -27,12 +65,17 @@ const (
 type RabbitMQ struct {
 	exporter metrics.Exporter
 	recover  chan string
+	mu       sync.Mutex
+	// queues are the consumer queues declared by this process. RabbitMQ can not list the queues over AMQP.
+	queues map[string]bool
+	// leader is the connection that holds the scheduler queue.
+	leader *amqp.Connection
 }
 
 // New ctor
 func New(exporter metrics.Exporter) *RabbitMQ {
 	connections = make(map[string]*amqp.Connection)
-	r := &RabbitMQ{exporter: exporter}
+	r := &RabbitMQ{exporter: exporter, queues: make(map[string]bool)}
 	return r
 }
 
@@ -43,7 +86,12 @@ func (r *RabbitMQ) NotifyRecover(ch chan string) chan string {
 }
 
 // Queue .
-func (r *RabbitMQ) Queue(service string, name string, message []byte) (err error) {
+func (r *RabbitMQ) Queue(service string, name string, message []byte) error {
+	return r.QueueAt(service, name, message, time.Time{})
+}
+
+// QueueAt .
+func (r *RabbitMQ) QueueAt(service string, name string, message []byte, at time.Time) (err error) {
 	start := time.Now()
 	defer func() {
 		if err != nil {
@@ -52,7 +100,7 @@ func (r *RabbitMQ) Queue(service string, name string, message []byte) (err error
 		}
 		r.exporter.SendSeconds(time.Since(start), service, name)
 	}()
-	channel, err := r.channel(service, name, nil)
+	channel, err := r.channel(service, name, nil, messaging.ConsumeOptions{})
 	if err != nil {
 		return err
 	}
@@ -64,7 +112,7 @@ func (r *RabbitMQ) Queue(service string, name string, message []byte) (err error
 	if err := r.exchange(channel, name); err != nil {
 		return err
 	}
-	if err := r.send(channel, name, name, message); err != nil {
+	if err := r.schedule(channel, name, message, nil, at); err != nil {
 		return err
 	}
 	log.Debug().Str("name", name).Msgf("queued")
@@ -72,12 +120,12 @@ func (r *RabbitMQ) Queue(service string, name string, message []byte) (err error
 }
 
 // Work .
-func (r *RabbitMQ) Work(service string, name string, callback func([]byte) bool) error {
-	channel, err := r.channel(service, name, callback)
+func (r *RabbitMQ) Work(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) error {
+	channel, err := r.channel(service, name, callback, opts)
 	if err != nil {
 		return err
 	}
-	err = r.consume(channel, service, name, callback, false)
+	err = r.consume(channel, service, name, callback, opts, false)
 	if err != nil {
 		return err
 	}
@@ -86,16 +134,23 @@ func (r *RabbitMQ) Work(service string, name string, callback func([]byte) bool)
 }
 
 // Publish .
-func (r *RabbitMQ) Publish(service string, name string, message []byte) (err error) {
+func (r *RabbitMQ) Publish(service string, name string, message []byte) error {
+	return r.PublishAt(service, name, message, time.Time{})
+}
+
+// PublishAt .
+func (r *RabbitMQ) PublishAt(service string, name string, message []byte, at time.Time) (err error) {
 	start := time.Now()
 	defer func() {
-		if err != nil {
+		if err == messaging.ErrUnroutable {
+			log.Warn().Str("name", name).Msg("published to no subscribers")
+		} else if err != nil {
 			log.Error().Err(err).Msg("could not be published")
 			r.exporter.IncSendError(service, name)
 		}
 		r.exporter.SendSeconds(time.Since(start), service, name)
 	}()
-	channel, err := r.channel(service, name, nil)
+	channel, err := r.channel(service, name, nil, messaging.ConsumeOptions{})
 	if err != nil {
 		return err
 	}
@@ -107,7 +162,10 @@ func (r *RabbitMQ) Publish(service string, name string, message []byte) (err err
 	if err := r.exchange(channel, name); err != nil {
 		return err
 	}
-	if err := r.send(channel, name, name, message); err != nil {
+	if err := r.route(channel, name); err != nil {
+		return err
+	}
+	if err := r.schedule(channel, name, message, amqp.Table{EventHeader: name}, at); err != nil {
 		return err
 	}
 	log.Debug().Str("name", name).Msgf("published")
@@ -115,12 +173,12 @@ func (r *RabbitMQ) Publish(service string, name string, message []byte) (err err
 }
 
 // Subscribe .
-func (r *RabbitMQ) Subscribe(service string, name string, callback func([]byte) bool) error {
-	channel, err := r.channel(service, name, callback)
+func (r *RabbitMQ) Subscribe(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) error {
+	channel, err := r.channel(service, name, callback, opts)
 	if err != nil {
 		return err
 	}
-	err = r.consume(channel, service, name, callback, true)
+	err = r.consume(channel, service, name, callback, opts, true)
 	if err != nil {
 		return err
 	}
@@ -138,6 +196,28 @@ func (r *RabbitMQ) getQueueName(service string, name string, pubSub bool) string
 	return queueName
 }
 
+// ttlLevel returns the shortest TTL level that is not shorter than the delay, the longest level if the delay is longer.
+func ttlLevel(delay time.Duration) int64 {
+	for _, ttl := range ttlLevels {
+		if time.Duration(ttl)*time.Millisecond >= delay {
+			return ttl
+		}
+	}
+	return ttlLevels[len(ttlLevels)-1]
+}
+
+// parkLevel returns the longest TTL level that is not longer than the delay, the shortest level if the delay is shorter.
+func parkLevel(delay time.Duration) int64 {
+	ttl := ttlLevels[0]
+	for _, level := range ttlLevels[1:] {
+		if time.Duration(level)*time.Millisecond > delay {
+			break
+		}
+		ttl = level
+	}
+	return ttl
+}
+
 func (r *RabbitMQ) getRetryExchangeName(exchangeName string) string {
 	return fmt.Sprintf("%s.%s", exchangeName, RetryQueueNameSuffix)
 }
@@ -0,0 +1,58 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+// ListenReplies consumes the exclusive reply queue over its own connection. The server deletes the queue once the
+// connection is closed, the queue is declared again once the connection is recovered.
+func (r *RabbitMQ) ListenReplies(queue string, callback messaging.Callback) error {
+	channel, err := r.connection(queue).Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open a channel. %v", err)
+	}
+	_, err = channel.QueueDeclare(
+		queue, // name
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare the reply queue. %v", err)
+	}
+	messages, err := channel.Consume(
+		queue, // queue
+		"",    // consumer
+		true,  // auto-ack
+		true,  // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,   // args
+	)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for d := range messages {
+			callback(&messaging.Delivery{Body: d.Body, Attempt: 1, QueuedAt: d.Timestamp})
+		}
+		log.Error().Str("name", queue).Msg("reply consumer stopped. deliveries closed")
+	}()
+	return nil
+}
+
+// Reply sends the message to the reply queue through the default exchange, no exchange is declared for the queue.
+func (r *RabbitMQ) Reply(queue string, message []byte) error {
+	channel, err := r.adminChannel()
+	if err != nil {
+		return err
+	}
+	defer r.closeAdminChannel(channel)
+	return r.send(channel, "", queue, message, nil, time.Now())
+}
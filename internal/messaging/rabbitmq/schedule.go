@@ -0,0 +1,76 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/turgayozgur/messageman/config"
+)
+
+// schedule sends the message to the exchange and waits for the confirmation, or holds it back if it is due later. The delay is rounded up to
+// the second and the message waits on the "<name>.delay.<ms>" queue of the longest TTL level that fits the delay, like the retries.
+// A message due later than the level carries its due time, the consumers park it on their retry queues until due.
+// With the delayed_exchange setting, the message waits on the "<name>.delayed" exchange of the
+// rabbitmq_delayed_message_exchange plugin instead.
+func (r *RabbitMQ) schedule(channel *amqp.Channel, name string, message []byte, headers amqp.Table, at time.Time) error {
+	now := time.Now()
+	if !at.After(now) {
+		return r.sendConfirmed(channel, name, name, true, message, headers, now)
+	}
+	delay := (at.Sub(now) + time.Second - 1) / time.Second * time.Second
+	if config.Cfg.RabbitMQ.DelayedExchange {
+		delayedExchangeName := fmt.Sprintf("%s.%s", name, DelayedExchangeNameSuffix)
+		if err := r.delayedExchange(channel, delayedExchangeName, name); err != nil {
+			return err
+		}
+		// the plugin returns every mandatory message, it routes them only once due.
+		delayed := amqp.Table{DelayHeader: delay.Milliseconds()}
+		for k, v := range headers {
+			delayed[k] = v
+		}
+		return r.sendConfirmed(channel, delayedExchangeName, name, false, message, delayed, now)
+	}
+	ttl := parkLevel(delay)
+	if ttl < delay.Milliseconds() {
+		parked := amqp.Table{DeliverAtHeader: now.Add(delay).UnixNano() / int64(time.Millisecond)}
+		for k, v := range headers {
+			parked[k] = v
+		}
+		headers = parked
+	}
+	delayQueueName := fmt.Sprintf("%s.%s.%d", name, DelayQueueNameSuffix, ttl)
+	if err := r.ttlQueue(channel, delayQueueName, name, name, ttl); err != nil {
+		return fmt.Errorf("failed to declare a delay queue. %v", err)
+	}
+	return r.sendConfirmed(channel, "", delayQueueName, true, message, headers, now)
+}
+
+// delayedExchange declares the delayed message exchange and binds it to the main exchange.
+func (r *RabbitMQ) delayedExchange(channel *amqp.Channel, delayedExchangeName string, name string) error {
+	err := channel.ExchangeDeclare(
+		delayedExchangeName, // name
+		"x-delayed-message", // type
+		true,                // durable
+		false,               // auto-deleted
+		false,               // internal
+		false,               // no-wait
+		amqp.Table{
+			"x-delayed-type": "direct",
+		}, // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare a delayed exchange. Make sure the rabbitmq_delayed_message_exchange plugin is enabled. %v", err)
+	}
+	err = channel.ExchangeBind(
+		name,                // destination
+		name,                // routing key
+		delayedExchangeName, // source
+		false,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to bind delayed exchange to exchange. %v", err)
+	}
+	return nil
+}
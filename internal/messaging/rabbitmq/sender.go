@@ -3,21 +3,65 @@ package rabbitmq
 import (
 	"fmt"
 	"github.com/streadway/amqp"
+	"github.com/turgayozgur/messageman/internal/messaging"
+	"time"
 )
 
-func (r *RabbitMQ) send(channel *amqp.Channel, name string, route string, message []byte) error {
+// send publishes a persistent message. The timestamp is the time the message is first queued and travels with its retries.
+func (r *RabbitMQ) send(channel *amqp.Channel, name string, route string, message []byte, headers amqp.Table, timestamp time.Time) error {
+	return r.publish(channel, name, route, false, publishing(message, headers, timestamp))
+}
+
+// sendConfirmed publishes a persistent message and waits for the broker to confirm it. The channel is put in confirm
+// mode, so it must not be used for anything else. A mandatory message that is not routed to any queue is returned by
+// the broker before the confirmation.
+func (r *RabbitMQ) sendConfirmed(channel *amqp.Channel, name string, route string, mandatory bool, message []byte, headers amqp.Table, timestamp time.Time) error {
+	if err := channel.Confirm(false); err != nil {
+		return fmt.Errorf("failed to put the channel in confirm mode. %v", err)
+	}
+	confirms := channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	returns := channel.NotifyReturn(make(chan amqp.Return, 1))
+	if err := r.publish(channel, name, route, mandatory, publishing(message, headers, timestamp)); err != nil {
+		return err
+	}
+	select {
+	case c, ok := <-confirms:
+		if !ok {
+			return fmt.Errorf("the channel is closed before the confirmation")
+		}
+		if !c.Ack {
+			return messaging.ErrNacked
+		}
+	case <-time.After(ConfirmTimeout):
+		return fmt.Errorf("the message is not confirmed in %v", ConfirmTimeout)
+	}
+	select {
+	case <-returns:
+		return messaging.ErrUnroutable
+	default:
+		return nil
+	}
+}
+
+func (r *RabbitMQ) publish(channel *amqp.Channel, name string, route string, mandatory bool, p amqp.Publishing) error {
 	err := channel.Publish(
-		name,  // exchange
-		route, // routing key
-		false, // mandatory
-		false, // immediate
-		amqp.Publishing{
-			ContentType:  "text/plain",
-			Body:         message,
-			DeliveryMode: amqp.Persistent,
-		})
+		name,      // exchange
+		route,     // routing key
+		mandatory, // mandatory
+		false,     // immediate
+		p)
 	if err != nil {
 		return fmt.Errorf("failed to publish a message. %v", err)
 	}
 	return nil
 }
+
+func publishing(message []byte, headers amqp.Table, timestamp time.Time) amqp.Publishing {
+	return amqp.Publishing{
+		ContentType:  "text/plain",
+		Headers:      headers,
+		Timestamp:    timestamp,
+		Body:         message,
+		DeliveryMode: amqp.Persistent,
+	}
+}
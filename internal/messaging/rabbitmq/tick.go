@@ -0,0 +1,39 @@
+package rabbitmq
+
+import (
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// LockTick returns true on the leader, the replica whose connection holds the exclusive scheduler queue. The others
+// fail to declare the queue until the connection of the leader is closed and the server deletes the queue.
+func (r *RabbitMQ) LockTick(schedule string, tick time.Time) (bool, error) {
+	connection := r.connection(DefaultConnectionName)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.leader == connection && !connection.IsClosed() {
+		return true, nil
+	}
+	channel, err := r.adminChannel()
+	if err != nil {
+		return false, err
+	}
+	defer r.closeAdminChannel(channel)
+	_, err = channel.QueueDeclare(
+		SchedulerQueueName, // name
+		false,              // durable
+		false,              // delete when unused
+		true,               // exclusive
+		false,              // no-wait
+		nil,                // arguments
+	)
+	if e, ok := err.(*amqp.Error); ok && e.Code == amqp.ResourceLocked {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	r.leader = connection
+	return true, nil
+}
@@ -0,0 +1,54 @@
+package messaging
+
+import (
+	"context"
+	"time"
+
+	"github.com/turgayozgur/messageman/config"
+	"golang.org/x/time/rate"
+)
+
+// ThrottledWait is the longest time a delivery of a throttled consumer waits for the rate limiter. The delivery is
+// not acknowledged while it waits, so the wait must stay below the ack timeouts of the brokers, the AckWait of nats and
+// the ClaimIdle of redis. The delivery is redelivered to another consumer otherwise.
+const ThrottledWait = time.Minute
+
+// RateLimiter throttles the requests to a worker or a subscriber. A throttled delivery waits in its handler,
+// so it stays unacknowledged on the broker instead of failing to the retry queue.
+type RateLimiter struct {
+	limiter *rate.Limiter
+}
+
+// NewRateLimiter creates the limiter from the rate limit settings of a service. nil if not limited.
+func NewRateLimiter(cfg *config.RateLimitConfig) *RateLimiter {
+	if cfg == nil || cfg.PerSecond <= 0 {
+		return nil
+	}
+	burst := cfg.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{limiter: rate.NewLimiter(rate.Limit(cfg.PerSecond), burst)}
+}
+
+// Wait blocks until the next request is allowed. A nil limiter never waits.
+func (l *RateLimiter) Wait() {
+	if l == nil {
+		return
+	}
+	// never fails, the context has no deadline and the burst is at least 1.
+	_ = l.limiter.Wait(context.Background())
+}
+
+// maxUnacked returns the deliveries a throttled consumer can hold without waiting longer than the ThrottledWait,
+// 0 if not limited.
+func maxUnacked(cfg *config.RateLimitConfig) int {
+	if cfg == nil || cfg.PerSecond <= 0 {
+		return 0
+	}
+	n := int(cfg.PerSecond*ThrottledWait.Seconds()) + cfg.Burst
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
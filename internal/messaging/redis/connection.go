@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/config"
+)
+
+// EnsureCanConnect to ensure connection is available to redis
+func (r *Redis) EnsureCanConnect() bool {
+	options, err := goredis.ParseURL(config.Cfg.Redis.Url)
+	if err != nil {
+		log.Error().Msgf("failed to parse the Redis url. %v", err)
+		return false
+	}
+	client := goredis.NewClient(options)
+	defer client.Close()
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		log.Error().Msgf("failed to connect to the Redis server. host:%s error:%v", options.Addr, err)
+		return false
+	}
+	log.Info().Msg("successfully connected to the Redis server")
+	return true
+}
+
+// connection returns the shared client. The client has its own connection pool.
+func (r *Redis) connection() (*goredis.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client != nil {
+		return r.client, nil
+	}
+	options, err := goredis.ParseURL(config.Cfg.Redis.Url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the Redis url. %v", err)
+	}
+	r.client = goredis.NewClient(options)
+	r.exporter.IncConnection(ConnectionName)
+	return r.client, nil
+}
+
+// watch pings the server and notifies the names of the stopped consumers once the server is reachable.
+// Consumers stop on connection errors, so the registrars register them again like after a RabbitMQ reconnect.
+func (r *Redis) watch(client *goredis.Client) {
+	r.mu.Lock()
+	if r.watching {
+		r.mu.Unlock()
+		return
+	}
+	r.watching = true
+	r.mu.Unlock()
+
+	go func() {
+		down := false
+		for {
+			time.Sleep(WaitToReconnectDuration)
+			if err := client.Ping(context.Background()).Err(); err != nil {
+				if !down {
+					down = true
+					r.exporter.DecConnection(ConnectionName)
+					log.Error().Str("name", ConnectionName).Msgf("connection closed, reason: %v", err)
+				}
+				r.exporter.IncError("connection")
+				continue
+			}
+			if down {
+				down = false
+				r.exporter.IncConnection(ConnectionName)
+				log.Info().Str("name", ConnectionName).Msg("successfully reconnected")
+			}
+			r.mu.Lock()
+			names := make([]string, 0, len(r.stopped))
+			for name := range r.stopped {
+				names = append(names, name)
+			}
+			r.stopped = make(map[string]bool)
+			r.mu.Unlock()
+			for _, name := range names {
+				if r.recover != nil {
+					r.recover <- name
+				}
+			}
+		}
+	}()
+}
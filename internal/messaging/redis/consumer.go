@@ -0,0 +1,211 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+// retried is the member of the delayed set and the payload of the retry stream entries.
+type retried struct {
+	ID       string
+	Body     []byte
+	Attempt  int
+	QueuedAt time.Time
+}
+
+// moveScript moves the due retries from the delayed set to the retry stream of the consumer group.
+var moveScript = goredis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 100)
+for _, member in ipairs(due) do
+	redis.call('XADD', KEYS[2], '*', 'payload', member)
+	redis.call('ZREM', KEYS[1], member)
+end
+return #due
+`)
+
+// consume starts the reader of the consumer group. The reader reads as many entries as the prefetch count
+// and hands them over to the handlers, the reader waits while every handler is busy.
+func (r *Redis) consume(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions, pubSub bool) error {
+	client, err := r.connection()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	groupName := r.getGroupName(service, name, pubSub)
+	stream := r.getStreamName(name)
+	retryStream := r.getRetryStreamName(groupName)
+
+	// like a newly bound queue, a new group gets the messages sent after it is created.
+	if err := r.group(ctx, client, stream, groupName, "$"); err != nil {
+		return err
+	}
+	if err := r.group(ctx, client, retryStream, groupName, "0"); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	if r.consumers[groupName] {
+		r.mu.Unlock()
+		return nil
+	}
+	r.consumers[groupName] = true
+	r.mu.Unlock()
+	r.watch(client)
+	r.exporter.IncConsumer(service, name)
+
+	handlers := make(chan struct{}, opts.Concurrency)
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.consumers, groupName)
+			r.stopped[name] = true
+			r.mu.Unlock()
+			r.exporter.DecConsumer(service, name)
+		}()
+		lastClaim := time.Time{}
+		lastMove := time.Time{}
+		for {
+			if time.Since(lastMove) >= MoveInterval {
+				due := fmt.Sprint(time.Now().UnixNano() / int64(time.Millisecond))
+				if err := moveScript.Run(ctx, client, []string{r.getDelayedSetName(groupName), retryStream}, due).Err(); err != nil {
+					log.Error().Str("service", service).Str("name", name).Msgf("consumer stopped. %v", err)
+					return
+				}
+				lastMove = time.Now()
+			}
+			if time.Since(lastClaim) >= ClaimInterval {
+				for _, s := range []string{stream, retryStream} {
+					if err := r.claim(ctx, client, service, name, s, groupName, callback); err != nil {
+						log.Error().Str("service", service).Str("name", name).Msgf("consumer stopped. %v", err)
+						return
+					}
+				}
+				lastClaim = time.Now()
+			}
+			streams, err := client.XReadGroup(ctx, &goredis.XReadGroupArgs{
+				Group:    groupName,
+				Consumer: r.consumer,
+				Streams:  []string{stream, retryStream, ">", ">"},
+				Count:    int64(opts.PrefetchCount()),
+				Block:    MoveInterval,
+			}).Result()
+			if err == goredis.Nil {
+				continue
+			}
+			if err != nil {
+				log.Error().Str("service", service).Str("name", name).Msgf("consumer stopped. %v", err)
+				return
+			}
+			for _, s := range streams {
+				for _, m := range s.Messages {
+					handlers <- struct{}{}
+					go func(stream string, m goredis.XMessage) {
+						defer func() { <-handlers }()
+						r.handle(ctx, client, service, name, stream, groupName, m, callback)
+					}(s.Stream, m)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (r *Redis) group(ctx context.Context, client *goredis.Client, stream string, groupName string, start string) error {
+	err := client.XGroupCreateMkStream(ctx, stream, groupName, start).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create a consumer group. %v", err)
+	}
+	return nil
+}
+
+// claim takes over the deliveries that another consumer of the group did not acknowledge in time.
+func (r *Redis) claim(ctx context.Context, client *goredis.Client, service string, name string, stream string, groupName string, callback messaging.Callback) error {
+	messages, _, err := client.XAutoClaim(ctx, &goredis.XAutoClaimArgs{
+		Stream:   stream,
+		Group:    groupName,
+		MinIdle:  ClaimIdle,
+		Start:    "0-0",
+		Count:    100,
+		Consumer: r.consumer,
+	}).Result()
+	if err != nil {
+		return err
+	}
+	for _, m := range messages {
+		log.Warn().Str("service", service).Str("name", name).Msgf("claimed a stuck delivery %s", m.ID)
+		r.handle(ctx, client, service, name, stream, groupName, m, callback)
+	}
+	return nil
+}
+
+func (r *Redis) handle(ctx context.Context, client *goredis.Client, service string, name string, stream string, groupName string, m goredis.XMessage, callback messaging.Callback) {
+	start := time.Now()
+	retryStream := r.getRetryStreamName(groupName)
+	if len(m.Values) == 0 {
+		// trimmed from the stream while pending.
+		if err := client.XAck(ctx, stream, groupName, m.ID).Err(); err != nil {
+			log.Error().Msgf("failed to send ACK. %v", err)
+		}
+		return
+	}
+	d := &retried{ID: m.ID, Attempt: 1, QueuedAt: idTime(m.ID)}
+	if stream == retryStream {
+		payload, _ := m.Values["payload"].(string)
+		if err := json.Unmarshal([]byte(payload), d); err != nil {
+			log.Error().Msgf("failed to read the retried message %s. %v", m.ID, err)
+		}
+	} else {
+		body, _ := m.Values["body"].(string)
+		d.Body = []byte(body)
+		d.Attempt = parseAttempt(m.Values["attempt"])
+	}
+
+	delivery := &messaging.Delivery{Body: d.Body, Attempt: d.Attempt, QueuedAt: d.QueuedAt}
+	result := r.invokeConsumerFunc(delivery, callback)
+	pipe := client.TxPipeline()
+	switch result.Action {
+	case messaging.Retry:
+		member, _ := json.Marshal(&retried{ID: m.ID, Body: d.Body, Attempt: d.Attempt + 1, QueuedAt: d.QueuedAt})
+		due := time.Now().Add(result.Delay).UnixNano() / int64(time.Millisecond)
+		pipe.ZAdd(ctx, r.getDelayedSetName(groupName), goredis.Z{Score: float64(due), Member: member})
+		r.exporter.IncConsumeError(service, name)
+	case messaging.Dead:
+		payload, _ := json.Marshal(messaging.NewDeadLetter(service, name, groupName, delivery, result.Failure))
+		pipe.XAdd(ctx, &goredis.XAddArgs{
+			Stream: r.getDeadStreamName(groupName),
+			Values: map[string]interface{}{"payload": payload},
+		})
+		r.exporter.IncConsumeError(service, name)
+	}
+	pipe.XAck(ctx, stream, groupName, m.ID)
+	if stream == retryStream {
+		// no other group reads the retry stream.
+		pipe.XDel(ctx, stream, m.ID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Error().Msgf("failed to send ACK. %v", err)
+	}
+	r.exporter.ConsumeSeconds(time.Since(start), service, name)
+}
+
+func (r *Redis) invokeConsumerFunc(d *messaging.Delivery, callback messaging.Callback) (result messaging.Result) {
+	defer func() {
+		if rc := recover(); rc != nil {
+			log.Error().Msgf("error when invoking the consumer function: %+v", rc)
+			result = messaging.Result{Action: messaging.Retry, Delay: RetryDelay}
+		}
+	}()
+	if callback == nil {
+		log.Warn().Msgf("callback function is nil. Make sure you have correct setup for your consumer")
+		return messaging.Result{Action: messaging.Retry, Delay: RetryDelay}
+	}
+	return callback(d)
+}
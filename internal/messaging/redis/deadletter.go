@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+// replayScript moves the dead letter to the retry stream of the consumer group unless another replay took it first.
+var replayScript = goredis.NewScript(`
+if redis.call('XDEL', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('XADD', KEYS[2], '*', 'payload', ARGV[2])
+return 1
+`)
+
+// DeadLetterQueues .
+func (r *Redis) DeadLetterQueues() ([]*messaging.DeadLetterQueue, error) {
+	client, err := r.connection()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	prefix := KeyPrefix + ":"
+	suffix := "." + DeadStreamNameSuffix
+	var queues []*messaging.DeadLetterQueue
+	iter := client.ScanType(ctx, 0, prefix+"*"+suffix, 100, "stream").Iterator()
+	for iter.Next(ctx) {
+		n, err := client.XLen(ctx, iter.Val()).Result()
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			continue
+		}
+		queue := strings.TrimSuffix(strings.TrimPrefix(iter.Val(), prefix), suffix)
+		queues = append(queues, &messaging.DeadLetterQueue{Queue: queue, Count: n})
+	}
+	return queues, iter.Err()
+}
+
+// PeekDeadLetters returns the oldest dead letters of the queue without removing them.
+func (r *Redis) PeekDeadLetters(queue string, limit int) ([]*messaging.DeadLetter, error) {
+	client, err := r.connection()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	stream := r.getDeadStreamName(queue)
+	var messages []goredis.XMessage
+	if limit > 0 {
+		messages, err = client.XRangeN(ctx, stream, "-", "+", int64(limit)).Result()
+	} else {
+		messages, err = client.XRange(ctx, stream, "-", "+").Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+	letters := make([]*messaging.DeadLetter, 0, len(messages))
+	for _, m := range messages {
+		l, err := readDeadLetter(m)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, l)
+	}
+	return letters, nil
+}
+
+// ReplayDeadLetter moves the dead letter to the retry stream, so only the consumer group it failed on gets it again.
+func (r *Redis) ReplayDeadLetter(queue string, id string, message []byte) error {
+	client, err := r.connection()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	stream := r.getDeadStreamName(queue)
+	messages, err := client.XRange(ctx, stream, id, id).Result()
+	if err != nil {
+		// an invalid stream ID.
+		if strings.HasPrefix(err.Error(), "ERR") {
+			return messaging.ErrDeadLetterNotFound
+		}
+		return err
+	}
+	if len(messages) == 0 {
+		return messaging.ErrDeadLetterNotFound
+	}
+	l, err := readDeadLetter(messages[0])
+	if err != nil {
+		return err
+	}
+	if message == nil {
+		message = l.Body
+	}
+	payload, _ := json.Marshal(&retried{ID: id, Body: message, Attempt: 1, QueuedAt: time.Now()})
+	moved, err := replayScript.Run(ctx, client, []string{stream, r.getRetryStreamName(queue)}, id, payload).Int()
+	if err != nil {
+		return err
+	}
+	if moved == 0 {
+		return messaging.ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// PurgeDeadLetters .
+func (r *Redis) PurgeDeadLetters(queue string) (int64, error) {
+	client, err := r.connection()
+	if err != nil {
+		return 0, err
+	}
+	ctx := context.Background()
+	stream := r.getDeadStreamName(queue)
+	pipe := client.TxPipeline()
+	n := pipe.XLen(ctx, stream)
+	pipe.Del(ctx, stream)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return n.Val(), nil
+}
+
+func readDeadLetter(m goredis.XMessage) (*messaging.DeadLetter, error) {
+	payload, _ := m.Values["payload"].(string)
+	l := &messaging.DeadLetter{}
+	if err := json.Unmarshal([]byte(payload), l); err != nil {
+		return nil, err
+	}
+	l.ID = m.ID
+	return l, nil
+}
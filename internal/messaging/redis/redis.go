@@ -0,0 +1,227 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/messaging"
+	"github.com/turgayozgur/messageman/internal/metrics"
+)
+
+const (
+	// KeyPrefix constant.
+	KeyPrefix = "messageman"
+	// RetryStreamNameSuffix constant.
+	RetryStreamNameSuffix = "retry"
+	// DelayedSetNameSuffix constant.
+	DelayedSetNameSuffix = "delayed"
+	// DeadStreamNameSuffix constant.
+	DeadStreamNameSuffix = messaging.DeadLetterQueueNameSuffix
+	// TickKeyPrefix constant. Followed by the schedule name and the unix time of the tick.
+	TickKeyPrefix = "messageman:tick"
+	// TickRetention constant. How long the claimed ticks of the schedules are kept.
+	TickRetention = 24 * time.Hour
+	// RetryDelay constant. Matches the TTL of the RabbitMQ retry queue. Used when the callback fails unexpectedly.
+	RetryDelay = 30 * time.Second
+	// ClaimIdle constant. Deliveries idle longer than this are claimed from the consumer that got stuck or died.
+	ClaimIdle = 2 * time.Minute
+	// ClaimInterval constant.
+	ClaimInterval = 30 * time.Second
+	// MoveInterval constant. How often the due retries are moved to the retry stream.
+	MoveInterval = time.Second
+	// WaitToReconnectDuration constant.
+	WaitToReconnectDuration = 5 * time.Second
+	// ConnectionName constant.
+	ConnectionName = "redis"
+)
+
+// Redis messager on top of Redis Streams. Queues and events are streams, workers of a queue
+// share one consumer group and every subscriber service gets its own group.
+type Redis struct {
+	exporter  metrics.Exporter
+	recover   chan string
+	mu        sync.Mutex
+	client    *goredis.Client
+	consumer  string
+	consumers map[string]bool
+	stopped   map[string]bool
+	watching  bool
+}
+
+// New ctor
+func New(exporter metrics.Exporter) *Redis {
+	hostname, _ := os.Hostname()
+	return &Redis{
+		exporter:  exporter,
+		consumer:  fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		consumers: make(map[string]bool),
+		stopped:   make(map[string]bool),
+	}
+}
+
+// NotifyRecover .
+func (r *Redis) NotifyRecover(ch chan string) chan string {
+	r.recover = ch
+	return r.recover
+}
+
+// Queue .
+func (r *Redis) Queue(service string, name string, message []byte) error {
+	return r.QueueAt(service, name, message, time.Time{})
+}
+
+// QueueAt .
+func (r *Redis) QueueAt(service string, name string, message []byte, at time.Time) (err error) {
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			log.Error().Err(err).Msg("could not be queued")
+			r.exporter.IncSendError(service, name)
+		}
+		r.exporter.SendSeconds(time.Since(start), service, name)
+	}()
+	if err := r.send(name, message, at); err != nil {
+		return err
+	}
+	log.Debug().Str("name", name).Msgf("queued")
+	return nil
+}
+
+// Work .
+func (r *Redis) Work(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) error {
+	return r.consume(service, name, callback, opts, false)
+}
+
+// Publish .
+func (r *Redis) Publish(service string, name string, message []byte) error {
+	return r.PublishAt(service, name, message, time.Time{})
+}
+
+// PublishAt .
+func (r *Redis) PublishAt(service string, name string, message []byte, at time.Time) (err error) {
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			log.Error().Err(err).Msg("could not be published")
+			r.exporter.IncSendError(service, name)
+		}
+		r.exporter.SendSeconds(time.Since(start), service, name)
+	}()
+	if err := r.send(name, message, at); err != nil {
+		return err
+	}
+	log.Debug().Str("name", name).Msgf("published")
+	return nil
+}
+
+// Subscribe .
+func (r *Redis) Subscribe(service string, name string, callback messaging.Callback, opts messaging.ConsumeOptions) error {
+	return r.consume(service, name, callback, opts, true)
+}
+
+func (r *Redis) send(name string, message []byte, at time.Time) error {
+	client, err := r.connection()
+	if err != nil {
+		return err
+	}
+	if at.After(time.Now()) {
+		return r.schedule(client, name, message, at)
+	}
+	err = client.XAdd(context.Background(), &goredis.XAddArgs{
+		Stream: r.getStreamName(name),
+		MaxLen: config.Cfg.Redis.MaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"body": message, "attempt": 1},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to publish a message. %v", err)
+	}
+	return nil
+}
+
+// schedule adds the message to the delayed set of every consumer group of the stream. The due messages are
+// moved to the retry stream of the group like the retries. Like a RabbitMQ exchange without bindings,
+// the message is dropped if no worker or subscriber registered yet.
+func (r *Redis) schedule(client *goredis.Client, name string, message []byte, at time.Time) error {
+	ctx := context.Background()
+	groups, err := client.XInfoGroups(ctx, r.getStreamName(name)).Result()
+	if err != nil && !strings.HasPrefix(err.Error(), "ERR no such key") {
+		return fmt.Errorf("failed to publish a message. %v", err)
+	}
+	if len(groups) == 0 {
+		log.Debug().Str("name", name).Msg("no consumer group, message dropped")
+		return nil
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	member, _ := json.Marshal(&retried{ID: hex.EncodeToString(id), Body: message, Attempt: 1, QueuedAt: time.Now()})
+	due := at.UnixNano() / int64(time.Millisecond)
+	pipe := client.TxPipeline()
+	for _, g := range groups {
+		pipe.ZAdd(ctx, r.getDelayedSetName(g.Name), goredis.Z{Score: float64(due), Member: member})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to publish a message. %v", err)
+	}
+	return nil
+}
+
+func (r *Redis) getStreamName(name string) string {
+	return fmt.Sprintf("%s:%s", KeyPrefix, name)
+}
+
+// getGroupName returns the consumer group. One group for a queue, one group per subscriber service for events.
+func (r *Redis) getGroupName(service string, name string, pubSub bool) string {
+	var groupName string
+	if pubSub {
+		groupName = fmt.Sprintf("%s.%s", name, service)
+	} else {
+		groupName = name
+	}
+	return groupName
+}
+
+// getRetryStreamName returns the stream that only the given group reads the retried messages from.
+func (r *Redis) getRetryStreamName(groupName string) string {
+	return fmt.Sprintf("%s:%s.%s", KeyPrefix, groupName, RetryStreamNameSuffix)
+}
+
+func (r *Redis) getDelayedSetName(groupName string) string {
+	return fmt.Sprintf("%s:%s.%s", KeyPrefix, groupName, DelayedSetNameSuffix)
+}
+
+// getDeadStreamName returns the stream that keeps the dead letters of the given group.
+func (r *Redis) getDeadStreamName(groupName string) string {
+	return fmt.Sprintf("%s:%s.%s", KeyPrefix, groupName, DeadStreamNameSuffix)
+}
+
+// idTime returns the time a stream entry is added from its ID. e.g. 1526919030474-55
+func idTime(id string) time.Time {
+	ms, err := strconv.ParseInt(strings.SplitN(id, "-", 2)[0], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+func parseAttempt(v interface{}) int {
+	s, _ := v.(string)
+	attempt, err := strconv.Atoi(s)
+	if err != nil || attempt < 1 {
+		return 1
+	}
+	return attempt
+}
@@ -0,0 +1,215 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/messaging"
+	"github.com/turgayozgur/messageman/internal/metrics"
+)
+
+// The tests run against the server of MESSAGEMAN_TEST_REDIS_URL, the default Redis url if not set. They are
+// skipped if the server is not reachable.
+
+const waitTimeout = 10 * time.Second
+
+func newTestRedis(t *testing.T) (*Redis, *goredis.Client) {
+	t.Helper()
+	if url := os.Getenv("MESSAGEMAN_TEST_REDIS_URL"); url != "" {
+		config.Cfg.Redis.Url = url
+	}
+	r := New(&metrics.NilExporter{})
+	client, err := r.connection()
+	if err != nil {
+		t.Skipf("no Redis server. %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Skipf("no Redis server. %v", err)
+	}
+	return r, client
+}
+
+// testName returns a name no other run uses and deletes the keys of it once the test is done.
+func testName(t *testing.T, r *Redis, client *goredis.Client) string {
+	t.Helper()
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	name := "test-" + hex.EncodeToString(b)
+	t.Cleanup(func() {
+		client.Del(context.Background(), r.getStreamName(name), r.getRetryStreamName(name), r.getDelayedSetName(name), r.getDeadStreamName(name))
+	})
+	return name
+}
+
+func receive(t *testing.T, ch chan *messaging.Delivery) *messaging.Delivery {
+	t.Helper()
+	select {
+	case d := <-ch:
+		return d
+	case <-time.After(waitTimeout):
+		t.Fatal("no delivery")
+		return nil
+	}
+}
+
+func deliveries(ch chan *messaging.Delivery) messaging.Callback {
+	return func(d *messaging.Delivery) messaging.Result {
+		ch <- d
+		return messaging.Result{Action: messaging.Ack}
+	}
+}
+
+func TestQueue(t *testing.T) {
+	r, client := newTestRedis(t)
+	name := testName(t, r, client)
+	ch := make(chan *messaging.Delivery, 1)
+	if err := r.Work("worker", name, deliveries(ch), messaging.ConsumeOptions{Concurrency: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Queue("api", name, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	d := receive(t, ch)
+	if string(d.Body) != "hello" || d.Attempt != 1 {
+		t.Fatalf("unexpected delivery. body:%s attempt:%d", d.Body, d.Attempt)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	r, client := newTestRedis(t)
+	name := testName(t, r, client)
+	t.Cleanup(func() {
+		for _, g := range []string{name + ".a", name + ".b"} {
+			client.Del(context.Background(), r.getRetryStreamName(g), r.getDelayedSetName(g), r.getDeadStreamName(g))
+		}
+	})
+	a := make(chan *messaging.Delivery, 1)
+	b := make(chan *messaging.Delivery, 1)
+	if err := r.Subscribe("a", name, deliveries(a), messaging.ConsumeOptions{Concurrency: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Subscribe("b", name, deliveries(b), messaging.ConsumeOptions{Concurrency: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Publish("api", name, []byte("event")); err != nil {
+		t.Fatal(err)
+	}
+	// every subscriber service gets its own copy.
+	for _, ch := range []chan *messaging.Delivery{a, b} {
+		if d := receive(t, ch); string(d.Body) != "event" {
+			t.Fatalf("unexpected body %s", d.Body)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	r, client := newTestRedis(t)
+	name := testName(t, r, client)
+	ch := make(chan *messaging.Delivery, 2)
+	callback := func(d *messaging.Delivery) messaging.Result {
+		ch <- d
+		if d.Attempt == 1 {
+			return messaging.Result{Action: messaging.Retry, Delay: 10 * time.Millisecond}
+		}
+		return messaging.Result{Action: messaging.Ack}
+	}
+	if err := r.Work("worker", name, callback, messaging.ConsumeOptions{Concurrency: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Queue("api", name, []byte("retried")); err != nil {
+		t.Fatal(err)
+	}
+	first := receive(t, ch)
+	second := receive(t, ch)
+	if second.Attempt != 2 || string(second.Body) != "retried" {
+		t.Fatalf("unexpected retry. body:%s attempt:%d", second.Body, second.Attempt)
+	}
+	if !second.QueuedAt.Equal(first.QueuedAt) {
+		t.Fatalf("the retry is queued at %v, expected %v", second.QueuedAt, first.QueuedAt)
+	}
+}
+
+func TestClaim(t *testing.T) {
+	r, client := newTestRedis(t)
+	name := testName(t, r, client)
+	ctx := context.Background()
+	stream := r.getStreamName(name)
+	if err := r.group(ctx, client, stream, name, "$"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.send(name, []byte("stuck"), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	// another consumer reads the message and dies before it acknowledges.
+	streams, err := client.XReadGroup(ctx, &goredis.XReadGroupArgs{
+		Group:    name,
+		Consumer: "dead",
+		Streams:  []string{stream, ">"},
+		Count:    1,
+	}).Result()
+	if err != nil || len(streams) != 1 || len(streams[0].Messages) != 1 {
+		t.Fatalf("failed to read the message. %v", err)
+	}
+	id := streams[0].Messages[0].ID
+	idle := (ClaimIdle + time.Minute).Milliseconds()
+	if err := client.Do(ctx, "XCLAIM", stream, name, "dead", 0, id, "IDLE", idle).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan *messaging.Delivery, 1)
+	if err := r.claim(ctx, client, "worker", name, stream, name, deliveries(ch)); err != nil {
+		t.Fatal(err)
+	}
+	if d := receive(t, ch); string(d.Body) != "stuck" {
+		t.Fatalf("unexpected body %s", d.Body)
+	}
+	pending, err := client.XPending(ctx, stream, name).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Count != 0 {
+		t.Fatalf("%d deliveries are still pending", pending.Count)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	r, client := newTestRedis(t)
+	name := testName(t, r, client)
+	recovered := r.NotifyRecover(make(chan string, 1))
+	ch := make(chan *messaging.Delivery, 1)
+	opts := messaging.ConsumeOptions{Concurrency: 1}
+	if err := r.Work("worker", name, deliveries(ch), opts); err != nil {
+		t.Fatal(err)
+	}
+	// the consumer stops once its group is gone, like on a connection error.
+	if err := client.XGroupDestroy(context.Background(), r.getStreamName(name), name).Err(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case n := <-recovered:
+		if n != name {
+			t.Fatalf("recovered %s, expected %s", n, name)
+		}
+	case <-time.After(waitTimeout + WaitToReconnectDuration):
+		t.Fatal("not recovered")
+	}
+	if err := r.Work("worker", name, deliveries(ch), opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Queue("api", name, []byte("after")); err != nil {
+		t.Fatal(err)
+	}
+	if d := receive(t, ch); string(d.Body) != "after" {
+		t.Fatalf("unexpected body %s", d.Body)
+	}
+}
@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+// ListenReplies subscribes to the reply channel of the queue. Nothing is kept on the server, the client subscribes
+// again once the connection is recovered and the replies sent meanwhile are dropped.
+func (r *Redis) ListenReplies(queue string, callback messaging.Callback) error {
+	client, err := r.connection()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	pubSub := client.Subscribe(ctx, r.getReplyChannelName(queue))
+	// waits for the confirmation, so the replies are not missed once the requests are sent.
+	if _, err := pubSub.Receive(ctx); err != nil {
+		pubSub.Close()
+		return fmt.Errorf("failed to subscribe to the replies. %v", err)
+	}
+	go func() {
+		for m := range pubSub.Channel() {
+			callback(&messaging.Delivery{Body: []byte(m.Payload), Attempt: 1})
+		}
+	}()
+	return nil
+}
+
+// Reply publishes the message on the reply channel of the queue.
+func (r *Redis) Reply(queue string, message []byte) error {
+	client, err := r.connection()
+	if err != nil {
+		return err
+	}
+	if err := client.Publish(context.Background(), r.getReplyChannelName(queue), message).Err(); err != nil {
+		return fmt.Errorf("failed to publish a reply. %v", err)
+	}
+	return nil
+}
+
+func (r *Redis) getReplyChannelName(queue string) string {
+	return fmt.Sprintf("%s:%s", KeyPrefix, queue)
+}
@@ -0,0 +1,21 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LockTick sets the key of the tick if not exists, only one replica succeeds. The key expires on its own.
+func (r *Redis) LockTick(schedule string, tick time.Time) (bool, error) {
+	client, err := r.connection()
+	if err != nil {
+		return false, err
+	}
+	return client.SetNX(context.Background(), r.getTickKey(schedule, tick), 1, TickRetention).Result()
+}
+
+// getTickKey returns the key of the tick. e.g. messageman:tick:nightly_report:1614556800
+func (r *Redis) getTickKey(schedule string, tick time.Time) string {
+	return fmt.Sprintf("%s:%s:%d", TickKeyPrefix, schedule, tick.Unix())
+}
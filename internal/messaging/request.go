@@ -0,0 +1,198 @@
+package messaging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/turgayozgur/messageman/config"
+)
+
+const (
+	// CorrelationIDHeader ties the reply to its request.
+	CorrelationIDHeader = "x-correlation-id"
+	// ReplyToHeader is the queue the worker sends the reply to.
+	ReplyToHeader = "x-reply-to"
+	// ReplyStatusCodeHeader is the status code of the worker on the reply.
+	ReplyStatusCodeHeader = "x-status-code"
+	// ReplyErrorHeader is the failure of the worker on the reply, if the job failed for good.
+	ReplyErrorHeader = "x-error"
+	// ReplyService is the service name of the reply queue consumer.
+	ReplyService = "messageman"
+	// DefaultRequestTimeout is the time a request waits for the reply if no timeout given.
+	DefaultRequestTimeout = 30 * time.Second
+)
+
+var (
+	// ErrRequestTimeout is returned if the reply of a request does not come back in time.
+	ErrRequestTimeout = errors.New("the reply of the request did not come back in time.")
+	// ErrRequestNotSupported is returned by the messagers that can not keep a reply queue per instance.
+	ErrRequestNotSupported = errors.New("request/reply is not supported by the broker.")
+)
+
+// unsafeName matches the characters that are not allowed in the queue names of every broker.
+var unsafeName = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Replier is implemented by the messagers that keep the reply queue only while its messageman instance is connected.
+// The other messagers would keep the queue of every instance that ever ran, they do not support request/reply.
+type Replier interface {
+	// ListenReplies consumes the reply queue. The broker removes the queue once the instance goes away.
+	ListenReplies(queue string, callback Callback) error
+	// Reply sends the message to the reply queue. The message is dropped if the queue is gone.
+	Reply(queue string, message []byte) error
+}
+
+// Reply is the response of the worker to a request.
+type Reply struct {
+	Body []byte
+	// StatusCode is the HTTP or the gRPC status code of the worker.
+	StatusCode int
+	// Error is the failure of the worker, if the job failed for good.
+	Error string
+}
+
+// Requester queues the messages of the requests and hands the replies back to the waiting callers. Every messageman
+// instance has its own reply queue, so the reply comes back to the instance that waits for it.
+type Requester struct {
+	messager Messager
+	wrapper  Wrapper
+	queue    string
+	mu       sync.Mutex
+	pending  map[string]chan *Reply
+}
+
+// NewRequester creates the requester with the reply queue of this instance, named after the host and the port.
+func NewRequester(m Messager, w Wrapper) *Requester {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = randomID()
+	}
+	return &Requester{
+		messager: m,
+		wrapper:  w,
+		queue:    "messageman.reply." + unsafeName.ReplaceAllString(hostname, "-") + "-" + config.Cfg.Port,
+		pending:  map[string]chan *Reply{},
+	}
+}
+
+// Queue returns the name of the reply queue.
+func (r *Requester) Queue() string {
+	return r.queue
+}
+
+// Listen consumes the reply queue.
+func (r *Requester) Listen() {
+	callback := func(d *Delivery) Result {
+		body, headers, err := r.wrapper.Unwrap(d.Body)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to read the reply")
+			return Result{Action: Ack}
+		}
+		id := string(headers[CorrelationIDHeader])
+		r.mu.Lock()
+		ch, ok := r.pending[id]
+		delete(r.pending, id)
+		r.mu.Unlock()
+		if !ok {
+			log.Warn().Str("id", id).Msg("reply dropped. The request is timed out or unknown")
+			return Result{Action: Ack}
+		}
+		statusCode, _ := strconv.Atoi(string(headers[ReplyStatusCodeHeader]))
+		ch <- &Reply{Body: body, StatusCode: statusCode, Error: string(headers[ReplyErrorHeader])}
+		return Result{Action: Ack}
+	}
+	rp, ok := r.messager.(Replier)
+	if !ok {
+		log.Info().Msg("request/reply is not supported by the broker")
+		return
+	}
+	if err := rp.ListenReplies(r.queue, callback); err != nil {
+		log.Error().Err(err).Msg("failed to listen the replies")
+		return
+	}
+	log.Info().Str("name", r.queue).Msg("listening the replies")
+}
+
+// Request queues the message with a correlation ID and the reply queue, then waits for the reply.
+func (r *Requester) Request(service string, name string, body []byte, headers map[string][]byte, timeout time.Duration) (*Reply, error) {
+	if _, ok := r.messager.(Replier); !ok {
+		return nil, ErrRequestNotSupported
+	}
+	id := randomID()
+	h := make(map[string][]byte, len(headers)+2)
+	for k, v := range headers {
+		h[k] = v
+	}
+	h[CorrelationIDHeader] = []byte(id)
+	h[ReplyToHeader] = []byte(r.queue)
+	message, err := r.wrapper.Wrap(body, h)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *Reply, 1)
+	r.mu.Lock()
+	r.pending[id] = ch
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, id)
+		r.mu.Unlock()
+	}()
+
+	if err := r.messager.Queue(service, name, message); err != nil {
+		return nil, err
+	}
+	select {
+	case reply := <-ch:
+		return reply, nil
+	case <-time.After(timeout):
+		return nil, ErrRequestTimeout
+	}
+}
+
+// reply sends the response of the worker back to the requester, if the message is a request.
+func reply(m Messager, w Wrapper, service string, message []byte, reply *Reply) {
+	rp, ok := m.(Replier)
+	if !ok {
+		return
+	}
+	_, headers, err := w.Unwrap(message)
+	if err != nil || len(headers[ReplyToHeader]) == 0 {
+		return
+	}
+	h := map[string][]byte{
+		CorrelationIDHeader:   headers[CorrelationIDHeader],
+		ReplyStatusCodeHeader: []byte(strconv.Itoa(reply.StatusCode)),
+	}
+	if reply.Error != "" {
+		h[ReplyErrorHeader] = []byte(reply.Error)
+	}
+	b, err := w.Wrap(reply.Body, h)
+	if err == nil {
+		err = rp.Reply(string(headers[ReplyToHeader]), b)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("service", service).Msg("failed to send the reply")
+	}
+}
+
+// isRequest returns true if the message waits for a reply.
+func isRequest(w Wrapper, message []byte) bool {
+	_, headers, err := w.Unwrap(message)
+	return err == nil && len(headers[ReplyToHeader]) > 0
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
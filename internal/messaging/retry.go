@@ -0,0 +1,69 @@
+package messaging
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/turgayozgur/messageman/config"
+)
+
+// MaxRetryDelay bounds the delay of the retries if the max delay is not set, the backoff would overflow otherwise.
+const MaxRetryDelay = 24 * time.Hour
+
+// RetryPolicy decides whether and when a failed message is delivered again.
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	Multiplier   float64
+	MaxDelay     time.Duration
+	Jitter       float64
+}
+
+// NewRetryPolicy creates the policy from the first configured retry settings. Unset values fall back
+// to the defaults, a fixed delay of 30 seconds forever.
+func NewRetryPolicy(cfgs ...*config.RetryConfig) *RetryPolicy {
+	p := &RetryPolicy{
+		InitialDelay: config.DefaultRetryDelayMs * time.Millisecond,
+		Multiplier:   1,
+	}
+	for _, cfg := range cfgs {
+		if cfg == nil {
+			continue
+		}
+		p.MaxAttempts = cfg.MaxAttempts
+		if cfg.InitialDelayMs > 0 {
+			p.InitialDelay = time.Duration(cfg.InitialDelayMs) * time.Millisecond
+		}
+		if cfg.Multiplier > 0 {
+			p.Multiplier = cfg.Multiplier
+		}
+		p.MaxDelay = time.Duration(cfg.MaxDelayMs) * time.Millisecond
+		p.Jitter = math.Min(math.Max(cfg.Jitter, 0), 1)
+		break
+	}
+	return p
+}
+
+// Next returns the delay before the message that failed on the given attempt is delivered again.
+// It returns false once the message used up its attempts.
+func (p *RetryPolicy) Next(attempt int) (time.Duration, bool) {
+	if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+	maxDelay := float64(MaxRetryDelay)
+	if p.MaxDelay > 0 {
+		maxDelay = float64(p.MaxDelay)
+	}
+	delay := float64(p.InitialDelay) * math.Pow(p.Multiplier, float64(attempt-1))
+	if math.IsNaN(delay) || math.IsInf(delay, 0) || delay > maxDelay {
+		delay = maxDelay
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (rand.Float64()*2 - 1)
+	}
+	return time.Duration(delay), true
+}
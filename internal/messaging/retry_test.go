@@ -0,0 +1,91 @@
+package messaging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/turgayozgur/messageman/config"
+)
+
+func TestRetryPolicyDefaults(t *testing.T) {
+	p := NewRetryPolicy(nil)
+	for _, attempt := range []int{1, 2, 100} {
+		delay, ok := p.Next(attempt)
+		if !ok || delay != config.DefaultRetryDelayMs*time.Millisecond {
+			t.Errorf("attempt %d: got %v %v, expected the default delay forever", attempt, delay, ok)
+		}
+	}
+}
+
+func TestRetryPolicyFirstConfigured(t *testing.T) {
+	p := NewRetryPolicy(nil, &config.RetryConfig{InitialDelayMs: 100}, &config.RetryConfig{InitialDelayMs: 200})
+	if delay, _ := p.Next(1); delay != 100*time.Millisecond {
+		t.Errorf("got %v, expected the delay of the first retry settings", delay)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := NewRetryPolicy(&config.RetryConfig{InitialDelayMs: 100, Multiplier: 2, MaxDelayMs: 500})
+	tests := []struct {
+		attempt int
+		delay   time.Duration
+	}{
+		{-1, 100 * time.Millisecond},
+		{0, 100 * time.Millisecond},
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 500 * time.Millisecond},
+		{1000, 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if delay, ok := p.Next(tt.attempt); !ok || delay != tt.delay {
+			t.Errorf("attempt %d: got %v %v, expected %v", tt.attempt, delay, ok, tt.delay)
+		}
+	}
+}
+
+func TestRetryPolicyMaxAttempts(t *testing.T) {
+	p := NewRetryPolicy(&config.RetryConfig{MaxAttempts: 3})
+	for attempt, retry := range map[int]bool{1: true, 2: true, 3: false, 4: false} {
+		if _, ok := p.Next(attempt); ok != retry {
+			t.Errorf("attempt %d: got %v, expected %v", attempt, ok, retry)
+		}
+	}
+}
+
+func TestRetryPolicyOverflow(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.RetryConfig
+		max  time.Duration
+	}{
+		{"default cap", &config.RetryConfig{InitialDelayMs: 1000, Multiplier: 10}, MaxRetryDelay},
+		{"max delay", &config.RetryConfig{InitialDelayMs: 1000, Multiplier: 10, MaxDelayMs: 60000}, time.Minute},
+	}
+	for _, tt := range tests {
+		p := NewRetryPolicy(tt.cfg)
+		for _, attempt := range []int{20, 400, 100000} {
+			if delay, ok := p.Next(attempt); !ok || delay != tt.max {
+				t.Errorf("%s, attempt %d: got %v %v, expected %v", tt.name, attempt, delay, ok, tt.max)
+			}
+		}
+	}
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	p := NewRetryPolicy(&config.RetryConfig{InitialDelayMs: 1000, Jitter: 0.2})
+	for i := 0; i < 1000; i++ {
+		delay, _ := p.Next(1)
+		if delay < 800*time.Millisecond || delay > 1200*time.Millisecond {
+			t.Fatalf("got %v, expected 1s ±20%%", delay)
+		}
+	}
+	// the jitter is at most 100%, the delay never goes negative.
+	p = NewRetryPolicy(&config.RetryConfig{InitialDelayMs: 1000, Jitter: 5})
+	for i := 0; i < 1000; i++ {
+		if delay, _ := p.Next(1); delay < 0 || delay > 2*time.Second {
+			t.Fatalf("got %v, expected between 0 and 2s", delay)
+		}
+	}
+}
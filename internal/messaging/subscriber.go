@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/rs/zerolog/log"
 	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/metrics"
 	pb "github.com/turgayozgur/messageman/pb/v1/gen"
 	"google.golang.org/grpc/status"
 	"net/http"
@@ -14,14 +15,16 @@ import (
 type SubscriberRegistrar struct {
 	messager   Messager
 	wrapper    Wrapper
+	exporter   metrics.Exporter
 	cfg        *config.EventConfig
 	httpClient *http.Client
 }
 
-func NewSubscriberRegistrar(m Messager, w Wrapper, cfg *config.EventConfig) *SubscriberRegistrar {
+func NewSubscriberRegistrar(m Messager, w Wrapper, exporter metrics.Exporter, cfg *config.EventConfig) *SubscriberRegistrar {
 	return &SubscriberRegistrar{
 		messager: m,
 		wrapper:  w,
+		exporter: exporter,
 		cfg:      cfg,
 		// Clients and Transports are safe for concurrent use by multiple goroutines
 		// and for efficiency should only be created once and re-used.
@@ -54,20 +57,70 @@ func (s *SubscriberRegistrar) registerSubscriber(c config.ServiceConfig) {
 		}
 	}
 
-	err := s.messager.Subscribe(service, name, func(body []byte) bool {
-		log.Debug().Str("body", string(body)).Msg("message received")
-		var ok bool
-		if c.Type == "gRPC" {
-			ok = s.handleGRPC(service, name, body)
+	policy := NewRetryPolicy(c.Retry)
+	failure := NewFailurePolicy(c.Failure)
+	limiter := NewRateLimiter(c.RateLimit)
+
+	if err := checkPattern(s.messager, name); err != nil {
+		log.Error().Str("name", name).Str("service", service).Msgf("failed to register the subscriber. %v", err)
+		return
+	}
+
+	var filter *Filter
+	if c.Filter != "" {
+		var err error
+		if filter, err = NewFilter(c.Filter); err != nil {
+			log.Error().Str("name", name).Str("service", service).Msgf("failed to register the subscriber. Invalid filter. %v", err)
+			return
+		}
+	}
+
+	transformer, err := NewTransformer(c.Transform)
+	if err != nil {
+		log.Error().Str("name", name).Str("service", service).Msgf("failed to register the subscriber. Invalid transform. %v", err)
+		return
+	}
+
+	opts := NewConsumeOptions(&c, s.wrapper)
+	if err := checkOrdering(s.messager, opts); err != nil {
+		log.Error().Str("name", name).Str("service", service).Msgf("failed to register the subscriber. %v", err)
+		return
+	}
+
+	err = s.messager.Subscribe(service, name, inOrder(opts, func(d *Delivery) Result {
+		body := d.Body
+		log.Debug().Str("body", string(body)).Int("attempt", d.Attempt).Msg("message received")
+		if filter != nil {
+			// a message that can not be unwrapped goes to the subscriber as it is.
+			if b, headers, err := s.wrapper.Unwrap(body); err == nil && !filter.Match(b, headers) {
+				log.Debug().Str("body", string(body)).Str("service", service).Str("name", name).Msg("message filtered")
+				s.exporter.IncFiltered(service, name)
+				return Result{Action: Ack}
+			}
+		}
+		limiter.Wait()
+		// the matched event of a pattern subscription.
+		event := name
+		if d.Name != "" {
+			event = d.Name
+		}
+		var f *Failure
+		if message, err := transformer.Transform(s.wrapper, body); err != nil {
+			log.Error().Str("body", string(body)).Str("service", service).Str("name", name).
+				Msgf("message failed. Failed to transform the body. %v", err)
+			f = transformFailure(err)
+		} else if c.Type == "gRPC" {
+			f = s.handleGRPC(service, event, message)
 		} else {
-			ok = s.handleREST(service, url, name, body)
+			f = s.handleREST(service, url, event, message)
 		}
-		if !ok {
-			return ok
+		if f != nil {
+			l := log.With().Str("body", string(body)).Str("service", service).Str("name", name).Logger()
+			return classify(failure, policy, d, f, l, "message")
 		}
 		log.Debug().Str("body", string(body)).Msg("successfully handled")
-		return true
-	})
+		return Result{Action: Ack}
+	}), opts)
 	if err != nil {
 		log.Error().Err(err).Msg("")
 	} else {
@@ -75,22 +128,23 @@ func (s *SubscriberRegistrar) registerSubscriber(c config.ServiceConfig) {
 	}
 }
 
-func (s *SubscriberRegistrar) handleREST(service string, url string, name string, body []byte) bool {
-	response, err := doRest(s.wrapper, s.httpClient, url, body)
+func (s *SubscriberRegistrar) handleREST(service string, url string, name string, body []byte) *Failure {
+	response, err := doRest(s.wrapper, s.httpClient, url, body, map[string]string{EventNameHeader: name})
 	if err != nil {
 		log.Error().Err(err).Str("body", string(body)).Str("v", service).Str("name", name).
 			Msgf("handle failed. An error occurred on http post. url:%s", url)
-		return false
+		return &Failure{Error: err.Error()}
 	}
 	if response.StatusCode >= 300 {
 		log.Error().Str("body", string(body)).Str("service", service).Str("name", name).
 			Msgf("handle failed. Non success status code %d on http post to subscriber. url:%s", response.StatusCode, url)
-		return false
+		return restFailure(response)
 	}
-	return true
+	defer response.Body.Close()
+	return nil
 }
 
-func (s *SubscriberRegistrar) handleGRPC(service, name string, body []byte) bool {
+func (s *SubscriberRegistrar) handleGRPC(service, name string, body []byte) *Failure {
 	err := doGRPC(s.wrapper, body, func(ctx context.Context, b []byte) error {
 		c := pb.NewHandlerServiceClient(gRPCClients[service])
 		_, err := c.Handle(ctx, &pb.HandleRequest{
@@ -103,10 +157,10 @@ func (s *SubscriberRegistrar) handleGRPC(service, name string, body []byte) bool
 		l := log.Error().Str("body", string(body)).Str("service", service).Str("name", name)
 		if s, ok := status.FromError(err); ok {
 			l.Msgf("handle failed. Non success gRPC status code %d on http post to subscriber. message:%s", s.Code(), s.Message())
-			return false
+			return grpcFailure(s)
 		}
 		l.Msgf("handle failed. Unknown error from gRPC endpoint. %v", err)
-		return false
+		return &Failure{Error: err.Error()}
 	}
-	return true
+	return nil
 }
@@ -0,0 +1,62 @@
+package messaging
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EventNameHeader tells a REST subscriber the name of the event it handles.
+const EventNameHeader = "x-event-name"
+
+// PatternSubscriber is implemented by the messagers that can subscribe to the events matching a pattern, like
+// order.* or order.#. The name of the matched event is set on the deliveries.
+type PatternSubscriber interface {
+	// CheckPattern returns an error if the pattern can not be served by the messager.
+	CheckPattern(pattern string) error
+}
+
+// IsPattern returns true if a word of the dot separated name is a wildcard. * matches exactly one word,
+// # matches zero or more words.
+func IsPattern(name string) bool {
+	for _, word := range strings.Split(name, ".") {
+		if word == "*" || word == "#" {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchTopic returns true if the event name matches the pattern.
+func MatchTopic(pattern string, name string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(name, "."))
+}
+
+func matchWords(pattern []string, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(name); i++ {
+			if matchWords(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(name) > 0 && matchWords(pattern[1:], name[1:])
+	}
+	return len(name) > 0 && pattern[0] == name[0] && matchWords(pattern[1:], name[1:])
+}
+
+// checkPattern returns an error if the subscriber listens to a pattern but the messager can not route by patterns.
+func checkPattern(m Messager, name string) error {
+	if !IsPattern(name) {
+		return nil
+	}
+	p, ok := m.(PatternSubscriber)
+	if !ok {
+		return fmt.Errorf("wildcard subscriptions are not supported by the broker")
+	}
+	return p.CheckPattern(name)
+}
@@ -0,0 +1,101 @@
+package messaging
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"order.created", "order.created", true},
+		{"order.created", "order.updated", false},
+		{"order.created", "order.created.eu", false},
+		{"order.*", "order.created", true},
+		{"order.*", "order", false},
+		{"order.*", "order.created.eu", false},
+		{"*.created", "order.created", true},
+		{"*.created", "created", false},
+		{"order.*.eu", "order.created.eu", true},
+		{"order.*.eu", "order.created.us", false},
+		{"*", "order", true},
+		{"*", "order.created", false},
+		{"order.#", "order", true},
+		{"order.#", "order.created", true},
+		{"order.#", "order.created.eu", true},
+		{"order.#", "orders.created", false},
+		{"#.eu", "eu", true},
+		{"#.eu", "order.created.eu", true},
+		{"#.eu", "order.created.us", false},
+		{"order.#.eu", "order.eu", true},
+		{"order.#.eu", "order.created.paid.eu", true},
+		{"order.#.eu", "order.created.paid.us", false},
+		{"#", "order.created.eu", true},
+		{"#", "", true},
+		{"#.#", "order", true},
+		{"*.#", "order", true},
+		{"#.*", "order.created", true},
+		{"order.#.*.eu", "order.x.eu", true},
+		{"order.#.*.eu", "order.eu", false},
+		// the words are compared as they are.
+		{"Order.*", "order.created", false},
+		{"order.*", "order.", true},
+		{"*.#", "", true},
+		{"order.created*", "order.created", false},
+	}
+	for _, tt := range tests {
+		if got := MatchTopic(tt.pattern, tt.name); got != tt.match {
+			t.Errorf("%q against %q: got %v, want %v", tt.pattern, tt.name, got, tt.match)
+		}
+	}
+}
+
+func TestIsPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern bool
+	}{
+		{"order.created", false},
+		{"order.*", true},
+		{"#", true},
+		{"order.#.eu", true},
+		{"order.created*", false},
+		{"order.#created", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsPattern(tt.name); got != tt.pattern {
+			t.Errorf("%q: got %v, want %v", tt.name, got, tt.pattern)
+		}
+	}
+}
+
+// patternRecorder can route the patterns other than #.
+type patternRecorder struct {
+	recorder
+}
+
+func (r *patternRecorder) CheckPattern(pattern string) error {
+	if pattern == "#" {
+		return errors.New("the pattern matches every event")
+	}
+	return nil
+}
+
+func TestCheckPattern(t *testing.T) {
+	if err := checkPattern(&recorder{}, "order.created"); err != nil {
+		t.Errorf("a name is refused. %v", err)
+	}
+	if err := checkPattern(&recorder{}, "order.*"); err == nil {
+		t.Error("a pattern is accepted by a broker that can not route it")
+	}
+	if err := checkPattern(&patternRecorder{}, "order.*"); err != nil {
+		t.Errorf("a pattern is refused. %v", err)
+	}
+	if err := checkPattern(&patternRecorder{}, "#"); err == nil {
+		t.Error("the error of the broker is not returned")
+	}
+}
@@ -0,0 +1,198 @@
+package messaging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/turgayozgur/messageman/config"
+)
+
+// placeholder is a {{body.<path>}} or a {{headers.<name>}} of a template.
+var placeholder = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+// Transformer reshapes the JSON body of a message before it is delivered. The stages run in order: the fields are
+// projected, renamed, the headers are set on the body and the template is rendered with the result.
+type Transformer struct {
+	fields   [][]string
+	rename   [][2][]string
+	headers  [][2]string
+	template string
+}
+
+// NewTransformer creates the transformer from the transform settings of a service. nil if not transformed.
+func NewTransformer(cfg *config.TransformConfig) (*Transformer, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	t := &Transformer{template: cfg.Template}
+	for _, f := range cfg.Fields {
+		if f == "" {
+			return nil, fmt.Errorf("empty field to keep")
+		}
+		t.fields = append(t.fields, strings.Split(f, "."))
+	}
+	// the renames run in the order of their fields, so the result does not change from run to run.
+	for _, from := range sortedKeys(cfg.Rename) {
+		to := cfg.Rename[from]
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("empty field to rename")
+		}
+		t.rename = append(t.rename, [2][]string{strings.Split(from, "."), strings.Split(to, ".")})
+	}
+	for _, header := range sortedKeys(cfg.Headers) {
+		field := cfg.Headers[header]
+		if header == "" || field == "" {
+			return nil, fmt.Errorf("empty header or field to set")
+		}
+		t.headers = append(t.headers, [2]string{header, field})
+	}
+	for _, m := range placeholder.FindAllStringSubmatch(cfg.Template, -1) {
+		if _, ok := parseField(m[1]); !ok {
+			return nil, fmt.Errorf("unknown field %q in the template, expected headers.<name> or body.<path>", m[1])
+		}
+	}
+	return t, nil
+}
+
+// Transform returns the message with the transformed body, the headers stay as they are. A nil transformer or one
+// without any stage returns the message as it is.
+func (t *Transformer) Transform(w Wrapper, message []byte) ([]byte, error) {
+	if t == nil || len(t.fields) == 0 && len(t.rename) == 0 && len(t.headers) == 0 && t.template == "" {
+		return message, nil
+	}
+	body, headers, err := w.Unwrap(message)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(body))
+	// keeps the large numbers as they are.
+	d.UseNumber()
+	jsonErr := d.Decode(&v)
+	if len(t.fields) > 0 || len(t.rename) > 0 || len(t.headers) > 0 {
+		m, ok := v.(map[string]interface{})
+		if jsonErr != nil || !ok {
+			return nil, fmt.Errorf("the body is not a JSON object")
+		}
+		v = t.reshape(m, headers)
+	} else if jsonErr != nil {
+		v = nil
+	}
+	if t.template != "" {
+		body = t.render(v, headers)
+	} else if body, err = json.Marshal(v); err != nil {
+		return nil, err
+	}
+	return w.Wrap(body, headers)
+}
+
+func (t *Transformer) reshape(m map[string]interface{}, headers map[string][]byte) map[string]interface{} {
+	if len(t.fields) > 0 {
+		projected := map[string]interface{}{}
+		for _, path := range t.fields {
+			if v, ok := get(m, path); ok {
+				set(projected, path, v)
+			}
+		}
+		m = projected
+	}
+	for _, r := range t.rename {
+		if v, ok := get(m, r[0]); ok {
+			remove(m, r[0])
+			set(m, r[1], v)
+		}
+	}
+	for _, h := range t.headers {
+		if v, ok := header(headers, h[0]); ok {
+			set(m, strings.Split(h[1], "."), string(v))
+		}
+	}
+	return m
+}
+
+// render replaces the placeholders of the template with the JSON values of the fields. A missing field is null.
+func (t *Transformer) render(body interface{}, headers map[string][]byte) []byte {
+	s := &scope{headers: headers, body: body}
+	return []byte(placeholder.ReplaceAllStringFunc(t.template, func(p string) string {
+		f, _ := parseField(placeholder.FindStringSubmatch(p)[1])
+		b, err := json.Marshal(f.eval(s))
+		if err != nil {
+			return "null"
+		}
+		return string(b)
+	}))
+}
+
+// transformFailure is not retried by default, the body will not transform on the next attempt either.
+func transformFailure(err error) *Failure {
+	return &Failure{StatusCode: http.StatusUnprocessableEntity, Error: err.Error()}
+}
+
+// parseField parses the headers.<name> and the body.<path> of the filters and the templates. body alone is the
+// whole body.
+func parseField(text string) (*field, bool) {
+	path := strings.Split(text, ".")
+	switch {
+	case path[0] == "headers" && len(path) == 2 && path[1] != "":
+		return &field{header: path[1]}, true
+	case path[0] == "body":
+		return &field{path: path[1:]}, true
+	}
+	return nil, false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func get(m map[string]interface{}, path []string) (interface{}, bool) {
+	var v interface{} = m
+	for _, name := range path {
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = o[name]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// set creates the missing objects on the path. A value that is not an object on the path is replaced.
+func set(m map[string]interface{}, path []string, v interface{}) {
+	for _, name := range path[:len(path)-1] {
+		o, ok := m[name].(map[string]interface{})
+		if !ok {
+			o = map[string]interface{}{}
+			m[name] = o
+		}
+		m = o
+	}
+	m[path[len(path)-1]] = v
+}
+
+// remove deletes the objects on the path too, if they are left empty.
+func remove(m map[string]interface{}, path []string) {
+	if len(path) > 1 {
+		o, ok := m[path[0]].(map[string]interface{})
+		if !ok {
+			return
+		}
+		remove(o, path[1:])
+		if len(o) > 0 {
+			return
+		}
+	}
+	delete(m, path[0])
+}
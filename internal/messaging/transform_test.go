@@ -0,0 +1,237 @@
+package messaging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/turgayozgur/messageman/config"
+)
+
+// transform runs the transform settings on the body and the headers, returns the transformed body.
+func transform(t *testing.T, cfg *config.TransformConfig, body string, headers map[string][]byte) (string, error) {
+	t.Helper()
+	tr, err := NewTransformer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &DefaultWrapper{}
+	message, err := w.Wrap([]byte(body), headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err = tr.Transform(w, message)
+	if err != nil {
+		return "", err
+	}
+	b, h, err := w.Unwrap(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != len(headers) {
+		t.Fatalf("the headers changed. got %v, want %v", h, headers)
+	}
+	return string(b), nil
+}
+
+func TestTransform(t *testing.T) {
+	headers := map[string][]byte{"X-Tenant": []byte("acme"), "x-absent": nil}
+	order := `{"id":7,"customer":{"name":"ayşe","tier":"gold"},"lines":[{"sku":"a"}],"paid":true}`
+	tests := []struct {
+		name string
+		cfg  *config.TransformConfig
+		body string
+		want string
+	}{
+		{
+			name: "projection",
+			cfg:  &config.TransformConfig{Fields: []string{"id", "customer.name", "missing", "customer.missing"}},
+			body: order,
+			want: `{"customer":{"name":"ayşe"},"id":7}`,
+		},
+		{
+			name: "projection of an object",
+			cfg:  &config.TransformConfig{Fields: []string{"customer", "lines"}},
+			body: order,
+			want: `{"customer":{"name":"ayşe","tier":"gold"},"lines":[{"sku":"a"}]}`,
+		},
+		{
+			name: "projection through a value that is not an object",
+			cfg:  &config.TransformConfig{Fields: []string{"id.deep", "lines.sku"}},
+			body: order,
+			want: `{}`,
+		},
+		{
+			name: "rename",
+			cfg:  &config.TransformConfig{Rename: map[string]string{"id": "order_id", "paid": "status.paid"}},
+			body: order,
+			want: `{"customer":{"name":"ayşe","tier":"gold"},"lines":[{"sku":"a"}],"order_id":7,"status":{"paid":true}}`,
+		},
+		{
+			name: "rename removes the emptied parents",
+			cfg:  &config.TransformConfig{Rename: map[string]string{"a.b.c": "c"}},
+			body: `{"a":{"b":{"c":1}},"d":2}`,
+			want: `{"c":1,"d":2}`,
+		},
+		{
+			name: "rename keeps the parents with other fields",
+			cfg:  &config.TransformConfig{Rename: map[string]string{"customer.name": "buyer"}},
+			body: order,
+			want: `{"buyer":"ayşe","customer":{"tier":"gold"},"id":7,"lines":[{"sku":"a"}],"paid":true}`,
+		},
+		{
+			name: "renames run in the order of their fields",
+			cfg:  &config.TransformConfig{Rename: map[string]string{"b": "c", "a": "b"}},
+			body: `{"a":1,"b":2}`,
+			want: `{"c":1}`,
+		},
+		{
+			name: "rename of a missing field",
+			cfg:  &config.TransformConfig{Rename: map[string]string{"missing": "found", "id.deep": "deep"}},
+			body: `{"id":7}`,
+			want: `{"id":7}`,
+		},
+		{
+			name: "rename over a value that is not an object",
+			cfg:  &config.TransformConfig{Rename: map[string]string{"id": "paid.id"}},
+			body: `{"id":7,"paid":true}`,
+			want: `{"paid":{"id":7}}`,
+		},
+		{
+			name: "projection runs before the rename",
+			cfg: &config.TransformConfig{
+				Fields: []string{"customer.name"},
+				Rename: map[string]string{"customer.name": "name"},
+			},
+			body: order,
+			want: `{"name":"ayşe"}`,
+		},
+		{
+			name: "headers into the body",
+			cfg:  &config.TransformConfig{Headers: map[string]string{"x-tenant": "meta.tenant", "x-absent": "absent", "x-missing": "missing"}},
+			body: `{"id":7,"meta":{"v":1}}`,
+			want: `{"id":7,"meta":{"tenant":"acme","v":1}}`,
+		},
+		{
+			name: "headers after the projection",
+			cfg: &config.TransformConfig{
+				Fields:  []string{"id"},
+				Headers: map[string]string{"x-tenant": "tenant"},
+			},
+			body: order,
+			want: `{"id":7,"tenant":"acme"}`,
+		},
+		{
+			name: "template",
+			cfg:  &config.TransformConfig{Template: `{"order":{{ body.id }},"who":{{body.customer.name}},"tenant":{{headers.X-TENANT}},"none":{{body.missing}},"absent":{{headers.x-absent}}}`},
+			body: order,
+			want: `{"order":7,"who":"ayşe","tenant":"acme","none":null,"absent":null}`,
+		},
+		{
+			name: "template of the whole body",
+			cfg:  &config.TransformConfig{Template: `{"data":{{body}}}`},
+			body: `{"b":1,"a":[1,2]}`,
+			want: `{"data":{"a":[1,2],"b":1}}`,
+		},
+		{
+			name: "template of the transformed body",
+			cfg: &config.TransformConfig{
+				Rename:   map[string]string{"id": "order.id"},
+				Template: `order {{body.order.id}} of {{body.id}}`,
+			},
+			body: order,
+			want: `order 7 of null`,
+		},
+		{
+			name: "template of a body that is not an object",
+			cfg:  &config.TransformConfig{Template: `{"items":{{body}},"first":{{body.0}}}`},
+			body: `[1,2]`,
+			want: `{"items":[1,2],"first":null}`,
+		},
+		{
+			name: "template of a body that is not JSON",
+			cfg:  &config.TransformConfig{Template: `{"body":{{body}},"tenant":{{headers.x-tenant}}}`},
+			body: `plain text`,
+			want: `{"body":null,"tenant":"acme"}`,
+		},
+		{
+			name: "large numbers",
+			cfg:  &config.TransformConfig{Fields: []string{"id", "amount"}},
+			body: `{"id":12345678901234567890123,"amount":0.10000000000000000555,"small":1e-400}`,
+			want: `{"amount":0.10000000000000000555,"id":12345678901234567890123}`,
+		},
+		{
+			name: "large numbers in a template",
+			cfg:  &config.TransformConfig{Template: `{{body.id}}`},
+			body: `{"id":9007199254740993}`,
+			want: `9007199254740993`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := transform(t, tt.cfg, tt.body, headers)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTransformNotObject(t *testing.T) {
+	cfgs := []*config.TransformConfig{
+		{Fields: []string{"id"}},
+		{Rename: map[string]string{"id": "order_id"}},
+		{Headers: map[string]string{"x-tenant": "tenant"}},
+	}
+	for _, body := range []string{`[1,2]`, `"text"`, `42`, `null`, `plain text`, ``} {
+		for _, cfg := range cfgs {
+			if _, err := transform(t, cfg, body, nil); err == nil || err.Error() != "the body is not a JSON object" {
+				t.Errorf("%q: expected the body to be rejected, got %v", body, err)
+			}
+		}
+	}
+}
+
+func TestTransformNone(t *testing.T) {
+	w := &DefaultWrapper{}
+	message, err := w.Wrap([]byte("not json"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cfg := range []*config.TransformConfig{nil, {}} {
+		tr, err := NewTransformer(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := tr.Transform(w, message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, message) {
+			t.Errorf("%v: the message changed to %s", cfg, got)
+		}
+	}
+}
+
+func TestNewTransformerErrors(t *testing.T) {
+	tests := []struct {
+		cfg *config.TransformConfig
+		err string
+	}{
+		{&config.TransformConfig{Fields: []string{"id", ""}}, "empty field to keep"},
+		{&config.TransformConfig{Rename: map[string]string{"id": ""}}, "empty field to rename"},
+		{&config.TransformConfig{Rename: map[string]string{"": "id"}}, "empty field to rename"},
+		{&config.TransformConfig{Headers: map[string]string{"x-tenant": ""}}, "empty header or field to set"},
+		{&config.TransformConfig{Template: `{{total}}`}, `unknown field "total" in the template`},
+		{&config.TransformConfig{Template: `{{headers}}`}, `unknown field "headers" in the template`},
+		{&config.TransformConfig{Template: `{{headers.a.b}}`}, `unknown field "headers.a.b" in the template`},
+	}
+	for _, tt := range tests {
+		_, err := NewTransformer(tt.cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%v: got %v, want %q", tt.cfg, err, tt.err)
+		}
+	}
+}
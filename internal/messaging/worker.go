@@ -4,12 +4,15 @@ import (
 	"context"
 	pb "github.com/turgayozgur/messageman/pb/v1/gen"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"io/ioutil"
 	"net/http"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/jobs"
 )
 
 const ContentType = "application/json"
@@ -20,15 +23,26 @@ var gRPCClients = map[string]*grpc.ClientConn{}
 type WorkerRegistrar struct {
 	messager   Messager
 	wrapper    Wrapper
+	jobs       jobs.Store
+	step       *PipelineStep
 	cfg        *config.QueueConfig
+	retry      *RetryPolicy
+	failure    *FailurePolicy
+	limiter    *RateLimiter
 	httpClient *http.Client
 }
 
-func NewWorkerRegistrar(m Messager, w Wrapper, cfg *config.QueueConfig) *WorkerRegistrar {
+func NewWorkerRegistrar(m Messager, w Wrapper, store jobs.Store, pipelines *Pipelines, cfg *config.QueueConfig) *WorkerRegistrar {
 	return &WorkerRegistrar{
 		messager: m,
 		wrapper:  w,
+		jobs:     store,
+		step:     pipelines.Step(cfg.Name),
 		cfg:      cfg,
+		// the worker's own retry settings take precedence over the queue's.
+		retry:   NewRetryPolicy(cfg.Worker.Retry, cfg.Retry),
+		failure: NewFailurePolicy(cfg.Worker.Failure),
+		limiter: NewRateLimiter(cfg.Worker.RateLimit),
 		// Clients and Transports are safe for concurrent use by multiple goroutines
 		// and for efficiency should only be created once and re-used.
 		httpClient: &http.Client{
@@ -55,20 +69,85 @@ func (wr *WorkerRegistrar) registerWorker() {
 		}
 	}
 
-	err := wr.messager.Work(service, name, func(body []byte) bool {
-		log.Debug().Str("body", string(body)).Msg("job received")
-		var ok bool
-		if cfg.Worker.Type == "gRPC" {
-			ok = wr.receiveGRPC(service, name, body)
+	opts := NewConsumeOptions(&cfg.Worker, wr.wrapper)
+	if batch := cfg.Worker.Batch; batch != nil {
+		if batch.Size < 1 || opts.Ordering != nil {
+			log.Error().Str("name", name).Str("service", service).
+				Msg("failed to register the worker. The batch needs a positive size and can not be ordered.")
+			return
+		}
+		// a batch fills up only if as many deliveries are handled at the same time.
+		if opts.Concurrency < batch.Size {
+			opts.Concurrency = batch.Size
+		}
+	}
+	if err := checkOrdering(wr.messager, opts); err != nil {
+		log.Error().Str("name", name).Str("service", service).Msgf("failed to register the worker. %v", err)
+		return
+	}
+	transformer, err := NewTransformer(cfg.Worker.Transform)
+	if err != nil {
+		log.Error().Str("name", name).Str("service", service).Msgf("failed to register the worker. Invalid transform. %v", err)
+		return
+	}
+
+	callback := func(d *Delivery) Result {
+		body := d.Body
+		log.Debug().Str("body", string(body)).Int("attempt", d.Attempt).Msg("job received")
+		wr.limiter.Wait()
+		request := isRequest(wr.wrapper, body)
+		var r *Reply
+		var f *Failure
+		if message, err := transformer.Transform(wr.wrapper, body); err != nil {
+			log.Error().Str("body", string(body)).Str("service", service).Str("name", name).
+				Msgf("job failed. Failed to transform the body. %v", err)
+			f = transformFailure(err)
+		} else if cfg.Worker.Type == "gRPC" {
+			r, f = wr.receiveGRPC(service, name, message, request)
 		} else {
-			ok = wr.receiveREST(service, url, name, body)
+			r, f = wr.receiveREST(service, url, name, message)
 		}
-		if !ok {
-			return ok
+		r, f, result := wr.finish(service, name, d, r, f)
+		wr.done(service, name, d, r, f, result)
+		return result
+	}
+	if cfg.Worker.Batch != nil {
+		batcher := NewBatcher(cfg.Worker.Batch, func(items []*BatchItem) {
+			wr.limiter.Wait()
+			if cfg.Worker.Type == "gRPC" {
+				wr.receiveBatchGRPC(service, name, items)
+			} else {
+				wr.receiveBatchREST(service, url, name, items)
+			}
+		})
+		callback = func(d *Delivery) Result {
+			log.Debug().Str("body", string(d.Body)).Int("attempt", d.Attempt).Msg("job received")
+			var f *Failure
+			if message, err := transformer.Transform(wr.wrapper, d.Body); err != nil {
+				log.Error().Str("body", string(d.Body)).Str("service", service).Str("name", name).
+					Msgf("job failed. Failed to transform the body. %v", err)
+				f = transformFailure(err)
+			} else {
+				// the batch is sent with the transformed body, the result is of the delivery.
+				item := *d
+				item.Body = message
+				f = batcher.Do(&item)
+			}
+			// the workers of the batches respond with no body per job.
+			var r *Reply
+			if f == nil {
+				r = &Reply{}
+				if cfg.Worker.Type != "gRPC" {
+					r.StatusCode = http.StatusOK
+				}
+			}
+			r, f, result := wr.finish(service, name, d, r, f)
+			wr.done(service, name, d, r, f, result)
+			return result
 		}
-		log.Debug().Str("body", string(body)).Msg("job succeeded")
-		return true
-	})
+	}
+
+	err = wr.messager.Work(service, name, inOrder(opts, trackJob(wr.jobs, wr.wrapper, name, callback)), opts)
 	if err != nil {
 		log.Error().Err(err).Msg("")
 	} else {
@@ -76,39 +155,192 @@ func (wr *WorkerRegistrar) registerWorker() {
 	}
 }
 
-func (wr *WorkerRegistrar) receiveREST(service string, url string, name string, body []byte) bool {
-	response, err := doRest(wr.wrapper, wr.httpClient, url, body)
+// finish moves the job along its pipeline, if any. A succeeded job that can not move along fails like an attempt.
+func (wr *WorkerRegistrar) finish(service string, name string, d *Delivery, r *Reply, f *Failure) (*Reply, *Failure, Result) {
+	result := wr.result(service, name, d, f)
+	err := wr.step.advance(wr.messager, wr.wrapper, service, name, d, r, result)
+	if err == nil {
+		return r, f, result
+	}
+	log.Error().Str("body", string(d.Body)).Str("service", service).Str("name", name).Msgf("job failed. %v", err)
+	f = &Failure{Error: err.Error()}
+	result = wr.result(service, name, d, f)
+	// a failure never fails to advance, it goes to the failure queue if the job failed for good.
+	wr.step.advance(wr.messager, wr.wrapper, service, name, d, nil, result)
+	return nil, f, result
+}
+
+// done sends the callbacks and the reply of the request once the job succeeded or failed for good. The callbacks and
+// the caller wait for the response or the last failure, not for the failed attempts.
+func (wr *WorkerRegistrar) done(service string, name string, d *Delivery, r *Reply, f *Failure, result Result) {
+	if result.Action == Retry {
+		return
+	}
+	sendCallback(wr.messager, wr.wrapper, wr.httpClient, service, name, d, r, result)
+	if isRequest(wr.wrapper, d.Body) {
+		if f != nil {
+			r = &Reply{StatusCode: f.StatusCode, Error: f.Error}
+		}
+		reply(wr.messager, wr.wrapper, service, d.Body, r)
+	}
+}
+
+// result tells the messager what to do with the delivery after the attempt. f is nil if the job succeeded.
+func (wr *WorkerRegistrar) result(service string, name string, d *Delivery, f *Failure) Result {
+	body := d.Body
+	if f != nil {
+		l := log.With().Str("body", string(body)).Str("service", service).Str("name", name).Logger()
+		return classify(wr.failure, wr.retry, d, f, l, "job")
+	}
+	log.Debug().Str("body", string(body)).Msg("job succeeded")
+	return Result{Action: Ack}
+}
+
+// receiveREST returns the response of the worker if the job succeeded, the failure otherwise.
+func (wr *WorkerRegistrar) receiveREST(service string, url string, name string, body []byte) (*Reply, *Failure) {
+	response, err := doRest(wr.wrapper, wr.httpClient, url, body, nil)
 	if err != nil {
 		log.Error().Err(err).Str("body", string(body)).Str("service", service).Str("name", name).
 			Msgf("job failed. An error occurred on http post. url:%s", url)
-		return false
+		return nil, &Failure{Error: err.Error()}
 	}
 	if response.StatusCode >= 300 {
 		log.Error().Str("body", string(body)).Str("service", service).Str("name", name).
 			Msgf("job failed. Non success status code %d on http post to worker. url:%s", response.StatusCode, url)
-		return false
+		return nil, restFailure(response)
+	}
+	defer response.Body.Close()
+	b, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		log.Error().Err(err).Str("body", string(body)).Str("service", service).Str("name", name).
+			Msgf("job failed. An error occurred on reading the response. url:%s", url)
+		return nil, &Failure{StatusCode: response.StatusCode, Error: err.Error()}
 	}
-	return true
+	return &Reply{Body: b, StatusCode: response.StatusCode}, nil
 }
 
-func (wr *WorkerRegistrar) receiveGRPC(service string, name string, body []byte) bool {
+// receiveGRPC returns the response of the worker if the job succeeded, the failure otherwise. The Reply of the
+// worker is called instead of the Receive for the messages of a request.
+func (wr *WorkerRegistrar) receiveGRPC(service string, name string, body []byte, request bool) (*Reply, *Failure) {
+	r := &Reply{}
 	err := doGRPC(wr.wrapper, body, func(ctx context.Context, b []byte) error {
 		c := pb.NewWorkerServiceClient(gRPCClients[service])
-		_, err := c.Receive(ctx, &pb.ReceiveRequest{
+		in := &pb.ReceiveRequest{
 			Name:    name,
 			Message: b,
-		})
+		}
+		if request {
+			response, err := c.Reply(ctx, in)
+			if err == nil {
+				r.Body = response.Message
+			}
+			// the workers built before the Reply call get the request like any other job, the reply has no body.
+			if status.Code(err) != codes.Unimplemented {
+				return err
+			}
+		}
+		_, err := c.Receive(ctx, in)
 		return err
 	})
 	if err != nil {
 		l := log.Err(err).Str("body", string(body)).Str("service", service).Str("name", name)
 		if s, ok := status.FromError(err); ok {
 			l.Msgf("job failed. Non success gRPC status code %d on http post to worker. message:%s", s.Code(), s.Message())
+			return nil, grpcFailure(s)
 		}
 		l.Msgf("job failed. Unknown error from gRPC endpoint. %v", err)
-		return false
+		return nil, &Failure{Error: err.Error()}
+	}
+	return r, nil
+}
+
+// receiveBatchREST posts the batch and sets the failure of every item. A failed request fails all items.
+func (wr *WorkerRegistrar) receiveBatchREST(service string, url string, name string, items []*BatchItem) {
+	messages := make([][]byte, 0, len(items))
+	for _, i := range items {
+		messages = append(messages, i.Delivery.Body)
+	}
+	failAll := func(f *Failure) {
+		for _, i := range items {
+			i.Failure = f
+		}
+	}
+	response, err := doRestBatch(wr.wrapper, wr.httpClient, url, messages)
+	if err != nil {
+		log.Error().Err(err).Int("count", len(items)).Str("service", service).Str("name", name).
+			Msgf("batch failed. An error occurred on http post. url:%s", url)
+		failAll(&Failure{Error: err.Error()})
+		return
+	}
+	if response.StatusCode >= 300 {
+		log.Error().Int("count", len(items)).Str("service", service).Str("name", name).
+			Msgf("batch failed. Non success status code %d on http post to worker. url:%s", response.StatusCode, url)
+		failAll(restFailure(response))
+		return
+	}
+	failures, err := restBatchFailures(response, len(items))
+	if err != nil {
+		log.Error().Err(err).Int("count", len(items)).Str("service", service).Str("name", name).
+			Msgf("batch failed. url:%s", url)
+		failAll(&Failure{StatusCode: response.StatusCode, Error: err.Error()})
+		return
+	}
+	for n, f := range failures {
+		if f != nil {
+			log.Error().Str("body", string(items[n].Delivery.Body)).Str("service", service).Str("name", name).
+				Msgf("job failed. Non success status code %d in the batch results. url:%s error:%s", f.StatusCode, url, f.Error)
+		}
+		items[n].Failure = f
+	}
+}
+
+// receiveBatchGRPC calls the ReceiveBatch of the worker and sets the failure of every item. A failed call fails all items.
+func (wr *WorkerRegistrar) receiveBatchGRPC(service string, name string, items []*BatchItem) {
+	failAll := func(f *Failure) {
+		for _, i := range items {
+			i.Failure = f
+		}
+	}
+	request := &pb.ReceiveBatchRequest{Name: name}
+	for _, i := range items {
+		body, headers, err := wr.wrapper.Unwrap(i.Delivery.Body)
+		if err != nil {
+			failAll(&Failure{Error: err.Error()})
+			return
+		}
+		item := &pb.ReceiveBatchItem{Message: body, Headers: make(map[string]string, len(headers))}
+		for k, v := range headers {
+			item.Headers[k] = string(v)
+		}
+		request.Items = append(request.Items, item)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(time.Second*60))
+	defer cancel()
+	response, err := pb.NewWorkerServiceClient(gRPCClients[service]).ReceiveBatch(ctx, request)
+	if err != nil {
+		l := log.Err(err).Int("count", len(items)).Str("service", service).Str("name", name)
+		if s, ok := status.FromError(err); ok {
+			l.Msgf("batch failed. Non success gRPC status code %d from worker. message:%s", s.Code(), s.Message())
+			failAll(grpcFailure(s))
+			return
+		}
+		l.Msgf("batch failed. Unknown error from gRPC endpoint. %v", err)
+		failAll(&Failure{Error: err.Error()})
+		return
+	}
+	for n, i := range items {
+		if n >= len(response.Results) {
+			log.Error().Str("body", string(i.Delivery.Body)).Str("service", service).Str("name", name).
+				Msg("job failed. No result in the batch results.")
+			i.Failure = noBatchResult()
+			continue
+		}
+		if r := response.Results[n]; codes.Code(r.Code) != codes.OK {
+			log.Error().Str("body", string(i.Delivery.Body)).Str("service", service).Str("name", name).
+				Msgf("job failed. Non success gRPC status code %d in the batch results. message:%s", r.Code, r.Message)
+			i.Failure = &Failure{StatusCode: int(r.Code), GRPC: true, Error: r.Message}
+		}
 	}
-	return true
 }
 
 func connGRPC(service string, addr string) error {
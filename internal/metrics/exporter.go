@@ -12,6 +12,7 @@ type Exporter interface {
 	Handle(ctx *fasthttp.RequestCtx)
 	IncSendError(string, string)
 	IncConsumeError(string, string)
+	IncFiltered(string, string)
 	IncError(string)
 	IncConsumer(string, string)
 	DecConsumer(string, string)
@@ -48,6 +49,9 @@ func (n *NilExporter) IncSendError(service string, name string) {}
 // IncConsumeError .
 func (n *NilExporter) IncConsumeError(service string, name string) {}
 
+// IncFiltered .
+func (n *NilExporter) IncFiltered(service string, name string) {}
+
 // IncError .
 func (n *NilExporter) IncError(service string) {}
 
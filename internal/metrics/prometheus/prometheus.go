@@ -16,6 +16,7 @@ type Prometheus struct {
 	handlerFn                fasthttp.RequestHandler
 	sendErrorCounterVec      *prometheus.CounterVec
 	consumeErrorCounterVec   *prometheus.CounterVec
+	filteredCounterVec       *prometheus.CounterVec
 	errorCounterVec          *prometheus.CounterVec
 	consumerGaugeVec         *prometheus.GaugeVec
 	connectionGaugeVec       *prometheus.GaugeVec
@@ -36,6 +37,11 @@ func New() *Prometheus {
 				Name: "messageman_consume_errors_total",
 				Help: "Total number of consume errors",
 			}, []string{"service", "name"}),
+		filteredCounterVec: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "messageman_filtered_total",
+				Help: "Total number of messages filtered out by subscribers",
+			}, []string{"service", "name"}),
 		errorCounterVec: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "messageman_errors_total",
@@ -74,6 +80,7 @@ func (p *Prometheus) Handle(ctx *fasthttp.RequestCtx) {
 		// register ours.
 		r.MustRegister(p.sendErrorCounterVec)
 		r.MustRegister(p.consumeErrorCounterVec)
+		r.MustRegister(p.filteredCounterVec)
 		r.MustRegister(p.errorCounterVec)
 		r.MustRegister(p.consumerGaugeVec)
 		r.MustRegister(p.connectionGaugeVec)
@@ -96,6 +103,11 @@ func (p *Prometheus) IncConsumeError(service string, name string) {
 	p.consumeErrorCounterVec.WithLabelValues(service, name).Inc()
 }
 
+// IncFiltered .
+func (p *Prometheus) IncFiltered(service string, name string) {
+	p.filteredCounterVec.WithLabelValues(service, name).Inc()
+}
+
 // IncError .
 func (p *Prometheus) IncError(service string) {
 	p.errorCounterVec.WithLabelValues(service).Inc()
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/turgayozgur/messageman/internal/logging"
 	"github.com/turgayozgur/messageman/internal/messaging"
@@ -12,7 +13,16 @@ import (
 	"github.com/turgayozgur/messageman/internal/metrics"
 
 	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/jobs"
+	jobsembedded "github.com/turgayozgur/messageman/internal/jobs/embedded"
+	jobsmemory "github.com/turgayozgur/messageman/internal/jobs/memory"
+	"github.com/turgayozgur/messageman/internal/messaging/embedded"
+	"github.com/turgayozgur/messageman/internal/messaging/jetstream"
+	"github.com/turgayozgur/messageman/internal/messaging/kafka"
+	"github.com/turgayozgur/messageman/internal/messaging/memory"
+	"github.com/turgayozgur/messageman/internal/messaging/postgres"
 	"github.com/turgayozgur/messageman/internal/messaging/rabbitmq"
+	"github.com/turgayozgur/messageman/internal/messaging/redis"
 	"github.com/turgayozgur/messageman/service"
 )
 
@@ -33,10 +43,12 @@ func main() {
 	// create metric exporter.
 	exporter := metrics.CreateExporter(config.Cfg.Metric.Enabled, config.Cfg.Metric.Exporter)
 
-	// initialize messager to queue messages sent.
-	m := rabbitmq.New(exporter)
 	// wrapper
 	w := &messaging.DefaultWrapper{}
+	// initialize messager to queue messages sent.
+	m := createMessager(exporter, w)
+	// the statuses of the jobs are tracked if a store is configured.
+	j := createJobStore()
 
 	// check the sidecar mode and service count
 	s := ""
@@ -47,39 +59,101 @@ func main() {
 		log.Info().Msg("mode: gateway")
 	}
 
-	initConsumers(m, w)
+	// replies of the requests come back on the reply queue of this instance.
+	r := messaging.NewRequester(m, w)
 
-	initRecover(m)
+	initConsumers(m, w, exporter, j, r)
 
-	service.NewServer(m, w, exporter, s).Listen()
+	initRecover(m, r)
+
+	service.NewServer(m, w, exporter, j, r, s).Listen()
 }
 
-func initConsumers(m messaging.Messager, w messaging.Wrapper) {
+// createMessager factory method
+func createMessager(exporter metrics.Exporter, w messaging.Wrapper) messaging.Messager {
+	switch config.Cfg.Broker {
+	case "nats":
+		log.Info().Msg("broker: nats")
+		return jetstream.New(exporter)
+	case "kafka":
+		log.Info().Msg("broker: kafka")
+		return kafka.New(exporter, w)
+	case "redis":
+		log.Info().Msg("broker: redis")
+		return redis.New(exporter)
+	case "postgres":
+		log.Info().Msg("broker: postgres")
+		return postgres.New(exporter)
+	case "embedded":
+		log.Info().Msg("broker: embedded")
+		return embedded.New(exporter)
+	case "memory":
+		log.Info().Msg("broker: memory")
+		return memory.New(exporter)
+	default:
+		log.Info().Msg("broker: rabbitmq")
+		return rabbitmq.New(exporter)
+	}
+}
+
+// createJobStore factory method. nil if the statuses of the jobs are not tracked.
+func createJobStore() jobs.Store {
+	ttl := time.Duration(config.Cfg.Jobs.TTLMs) * time.Millisecond
+	switch config.Cfg.Jobs.Store {
+	case "memory":
+		log.Info().Msg("job store: memory")
+		return jobsmemory.New(ttl)
+	case "embedded":
+		log.Info().Msg("job store: embedded")
+		return jobsembedded.New(config.Cfg.Jobs.Path, ttl)
+	default:
+		return nil
+	}
+}
+
+func initConsumers(m messaging.Messager, w messaging.Wrapper, exporter metrics.Exporter, j jobs.Store, r *messaging.Requester) {
 	go func() {
 		if !config.IsSidecar() { // already waited on main method for sidecar mode.
 			// wait for the connection to establish.
 			waitfor.True(m.EnsureCanConnect)
 		}
+		r.Listen()
+		p, err := messaging.NewPipelines(config.Cfg.Pipelines)
+		if err != nil {
+			log.Error().Msgf("failed to register the pipelines. %v", err)
+		}
 		for _, s := range config.Cfg.Events {
 			// register subscribers if any.
-			sr := messaging.NewSubscriberRegistrar(m, w, s)
+			sr := messaging.NewSubscriberRegistrar(m, w, exporter, s)
 			sr.RegisterSubscribers()
 			subscriberRegistrars[s.Name] = sr
 		}
 		for _, s := range config.Cfg.Queues {
 			// register workers if any.
-			wr := messaging.NewWorkerRegistrar(m, w, s)
+			wr := messaging.NewWorkerRegistrar(m, w, j, p, s)
 			wr.RegisterWorker()
 			workerRegistrars[s.Name] = wr
 		}
+		for _, s := range config.Cfg.Schedules {
+			// fire the recurring jobs if any.
+			cr, err := messaging.NewCronRegistrar(m, w, s)
+			if err != nil {
+				log.Error().Msgf("failed to register the schedule. %v", err)
+				continue
+			}
+			cr.RegisterCron()
+		}
 	}()
 }
 
-func initRecover(m messaging.Messager) {
+func initRecover(m messaging.Messager, r *messaging.Requester) {
 	go func() {
 		ch := make(chan string)
 		for {
 			name := <-m.NotifyRecover(ch)
+			if name == r.Queue() {
+				r.Listen()
+			}
 			if v, ok := subscriberRegistrars[name]; ok {
 				v.RegisterSubscribers()
 			}
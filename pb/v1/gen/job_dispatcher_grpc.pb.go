@@ -7,7 +7,6 @@ import (
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
-	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
 // This is a compile-time assertion to ensure that this generated file
@@ -19,7 +18,15 @@ const _ = grpc.SupportPackageIsVersion7
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type JobDispatcherServiceClient interface {
-	Queue(ctx context.Context, in *QueueRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Queue(ctx context.Context, in *QueueRequest, opts ...grpc.CallOption) (*QueueResponse, error)
+	QueueBatch(ctx context.Context, in *QueueBatchRequest, opts ...grpc.CallOption) (*QueueBatchResponse, error)
+	// queues the message and waits for the response of the worker.
+	Request(ctx context.Context, in *RequestRequest, opts ...grpc.CallOption) (*RequestResponse, error)
+	// returns the last known status of a queued job. NOT_FOUND if unknown or expired.
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// revokes a queued or a retrying job, its deliveries are acked without calling the worker. NOT_FOUND if unknown or
+	// expired, FAILED_PRECONDITION if in progress or finished.
+	CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*Job, error)
 }
 
 type jobDispatcherServiceClient struct {
@@ -30,8 +37,8 @@ func NewJobDispatcherServiceClient(cc grpc.ClientConnInterface) JobDispatcherSer
 	return &jobDispatcherServiceClient{cc}
 }
 
-func (c *jobDispatcherServiceClient) Queue(ctx context.Context, in *QueueRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
-	out := new(emptypb.Empty)
+func (c *jobDispatcherServiceClient) Queue(ctx context.Context, in *QueueRequest, opts ...grpc.CallOption) (*QueueResponse, error) {
+	out := new(QueueResponse)
 	err := c.cc.Invoke(ctx, "/messageman.v1.JobDispatcherService/Queue", in, out, opts...)
 	if err != nil {
 		return nil, err
@@ -39,11 +46,55 @@ func (c *jobDispatcherServiceClient) Queue(ctx context.Context, in *QueueRequest
 	return out, nil
 }
 
+func (c *jobDispatcherServiceClient) QueueBatch(ctx context.Context, in *QueueBatchRequest, opts ...grpc.CallOption) (*QueueBatchResponse, error) {
+	out := new(QueueBatchResponse)
+	err := c.cc.Invoke(ctx, "/messageman.v1.JobDispatcherService/QueueBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobDispatcherServiceClient) Request(ctx context.Context, in *RequestRequest, opts ...grpc.CallOption) (*RequestResponse, error) {
+	out := new(RequestResponse)
+	err := c.cc.Invoke(ctx, "/messageman.v1.JobDispatcherService/Request", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobDispatcherServiceClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := c.cc.Invoke(ctx, "/messageman.v1.JobDispatcherService/GetJob", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobDispatcherServiceClient) CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := c.cc.Invoke(ctx, "/messageman.v1.JobDispatcherService/CancelJob", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // JobDispatcherServiceServer is the server API for JobDispatcherService service.
 // All implementations must embed UnimplementedJobDispatcherServiceServer
 // for forward compatibility
 type JobDispatcherServiceServer interface {
-	Queue(context.Context, *QueueRequest) (*emptypb.Empty, error)
+	Queue(context.Context, *QueueRequest) (*QueueResponse, error)
+	QueueBatch(context.Context, *QueueBatchRequest) (*QueueBatchResponse, error)
+	// queues the message and waits for the response of the worker.
+	Request(context.Context, *RequestRequest) (*RequestResponse, error)
+	// returns the last known status of a queued job. NOT_FOUND if unknown or expired.
+	GetJob(context.Context, *GetJobRequest) (*Job, error)
+	// revokes a queued or a retrying job, its deliveries are acked without calling the worker. NOT_FOUND if unknown or
+	// expired, FAILED_PRECONDITION if in progress or finished.
+	CancelJob(context.Context, *CancelJobRequest) (*Job, error)
 	mustEmbedUnimplementedJobDispatcherServiceServer()
 }
 
@@ -51,9 +102,21 @@ type JobDispatcherServiceServer interface {
 type UnimplementedJobDispatcherServiceServer struct {
 }
 
-func (UnimplementedJobDispatcherServiceServer) Queue(context.Context, *QueueRequest) (*emptypb.Empty, error) {
+func (UnimplementedJobDispatcherServiceServer) Queue(context.Context, *QueueRequest) (*QueueResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Queue not implemented")
 }
+func (UnimplementedJobDispatcherServiceServer) QueueBatch(context.Context, *QueueBatchRequest) (*QueueBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueueBatch not implemented")
+}
+func (UnimplementedJobDispatcherServiceServer) Request(context.Context, *RequestRequest) (*RequestResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Request not implemented")
+}
+func (UnimplementedJobDispatcherServiceServer) GetJob(context.Context, *GetJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedJobDispatcherServiceServer) CancelJob(context.Context, *CancelJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelJob not implemented")
+}
 func (UnimplementedJobDispatcherServiceServer) mustEmbedUnimplementedJobDispatcherServiceServer() {}
 
 // UnsafeJobDispatcherServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -85,6 +148,78 @@ func _JobDispatcherService_Queue_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _JobDispatcherService_QueueBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueueBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobDispatcherServiceServer).QueueBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/messageman.v1.JobDispatcherService/QueueBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobDispatcherServiceServer).QueueBatch(ctx, req.(*QueueBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobDispatcherService_Request_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobDispatcherServiceServer).Request(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/messageman.v1.JobDispatcherService/Request",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobDispatcherServiceServer).Request(ctx, req.(*RequestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobDispatcherService_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobDispatcherServiceServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/messageman.v1.JobDispatcherService/GetJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobDispatcherServiceServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JobDispatcherService_CancelJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobDispatcherServiceServer).CancelJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/messageman.v1.JobDispatcherService/CancelJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobDispatcherServiceServer).CancelJob(ctx, req.(*CancelJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // JobDispatcherService_ServiceDesc is the grpc.ServiceDesc for JobDispatcherService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -96,6 +231,22 @@ var JobDispatcherService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Queue",
 			Handler:    _JobDispatcherService_Queue_Handler,
 		},
+		{
+			MethodName: "QueueBatch",
+			Handler:    _JobDispatcherService_QueueBatch_Handler,
+		},
+		{
+			MethodName: "Request",
+			Handler:    _JobDispatcherService_Request_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _JobDispatcherService_GetJob_Handler,
+		},
+		{
+			MethodName: "CancelJob",
+			Handler:    _JobDispatcherService_CancelJob_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pb/v1/job_dispatcher.proto",
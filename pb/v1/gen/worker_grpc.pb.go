@@ -20,6 +20,11 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type WorkerServiceClient interface {
 	Receive(ctx context.Context, in *ReceiveRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// called instead of Receive if the batch of the worker is configured.
+	ReceiveBatch(ctx context.Context, in *ReceiveBatchRequest, opts ...grpc.CallOption) (*ReceiveBatchResponse, error)
+	// called instead of Receive for the messages of a request, the message of the response is returned to the caller.
+	// a worker that returns Unimplemented gets the Receive call instead and replies with an empty message.
+	Reply(ctx context.Context, in *ReceiveRequest, opts ...grpc.CallOption) (*ReplyResponse, error)
 }
 
 type workerServiceClient struct {
@@ -39,11 +44,34 @@ func (c *workerServiceClient) Receive(ctx context.Context, in *ReceiveRequest, o
 	return out, nil
 }
 
+func (c *workerServiceClient) ReceiveBatch(ctx context.Context, in *ReceiveBatchRequest, opts ...grpc.CallOption) (*ReceiveBatchResponse, error) {
+	out := new(ReceiveBatchResponse)
+	err := c.cc.Invoke(ctx, "/messageman.v1.WorkerService/ReceiveBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workerServiceClient) Reply(ctx context.Context, in *ReceiveRequest, opts ...grpc.CallOption) (*ReplyResponse, error) {
+	out := new(ReplyResponse)
+	err := c.cc.Invoke(ctx, "/messageman.v1.WorkerService/Reply", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // WorkerServiceServer is the server API for WorkerService service.
 // All implementations must embed UnimplementedWorkerServiceServer
 // for forward compatibility
 type WorkerServiceServer interface {
 	Receive(context.Context, *ReceiveRequest) (*emptypb.Empty, error)
+	// called instead of Receive if the batch of the worker is configured.
+	ReceiveBatch(context.Context, *ReceiveBatchRequest) (*ReceiveBatchResponse, error)
+	// called instead of Receive for the messages of a request, the message of the response is returned to the caller.
+	// a worker that returns Unimplemented gets the Receive call instead and replies with an empty message.
+	Reply(context.Context, *ReceiveRequest) (*ReplyResponse, error)
 	mustEmbedUnimplementedWorkerServiceServer()
 }
 
@@ -54,6 +82,12 @@ type UnimplementedWorkerServiceServer struct {
 func (UnimplementedWorkerServiceServer) Receive(context.Context, *ReceiveRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Receive not implemented")
 }
+func (UnimplementedWorkerServiceServer) ReceiveBatch(context.Context, *ReceiveBatchRequest) (*ReceiveBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReceiveBatch not implemented")
+}
+func (UnimplementedWorkerServiceServer) Reply(context.Context, *ReceiveRequest) (*ReplyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reply not implemented")
+}
 func (UnimplementedWorkerServiceServer) mustEmbedUnimplementedWorkerServiceServer() {}
 
 // UnsafeWorkerServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -85,6 +119,42 @@ func _WorkerService_Receive_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WorkerService_ReceiveBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReceiveBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerServiceServer).ReceiveBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/messageman.v1.WorkerService/ReceiveBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServiceServer).ReceiveBatch(ctx, req.(*ReceiveBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WorkerService_Reply_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReceiveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerServiceServer).Reply(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/messageman.v1.WorkerService/Reply",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServiceServer).Reply(ctx, req.(*ReceiveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // WorkerService_ServiceDesc is the grpc.ServiceDesc for WorkerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -96,6 +166,14 @@ var WorkerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Receive",
 			Handler:    _WorkerService_Receive_Handler,
 		},
+		{
+			MethodName: "ReceiveBatch",
+			Handler:    _WorkerService_ReceiveBatch_Handler,
+		},
+		{
+			MethodName: "Reply",
+			Handler:    _WorkerService_Reply_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pb/v1/worker.proto",
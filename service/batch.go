@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/messaging"
+	pb "github.com/turgayozgur/messageman/pb/v1/gen"
+	"github.com/valyala/fasthttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// BatchRequestModel .
+type BatchRequestModel struct {
+	Messages []*BatchItemModel `json:"messages"`
+}
+
+// BatchItemModel is a message of a batch. The headers are added to the ones proxied from the request.
+type BatchItemModel struct {
+	Name    string            `json:"name"`
+	Body    json.RawMessage   `json:"body"`
+	Headers map[string]string `json:"headers"`
+}
+
+// BatchResponseModel has the results in the order of the messages.
+type BatchResponseModel struct {
+	Results []*BatchResultModel `json:"results"`
+	Failed  int                 `json:"failed"`
+}
+
+// BatchResultModel .
+type BatchResultModel struct {
+	Name   string `json:"name"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// QueueBatchREST push many messages to workers at once.
+func (s *Server) QueueBatchREST(ctx *fasthttp.RequestCtx) {
+	s.sendBatchREST(ctx, false)
+}
+
+// PublishBatchREST push many messages to subscribers at once.
+func (s *Server) PublishBatchREST(ctx *fasthttp.RequestCtx) {
+	s.sendBatchREST(ctx, true)
+}
+
+// QueueBatch push many messages to workers at once by using gRPC.
+func (s *Server) QueueBatch(ctx context.Context, in *pb.QueueBatchRequest) (*pb.QueueBatchResponse, error) {
+	items := make([]*BatchItemModel, 0, len(in.Items))
+	for _, i := range in.Items {
+		items = append(items, &BatchItemModel{Name: i.Name, Body: i.Message, Headers: i.Headers})
+	}
+	results, err := s.sendBatchGRPC(ctx, items, false)
+	if err != nil {
+		return nil, err
+	}
+	response := &pb.QueueBatchResponse{}
+	for _, r := range results {
+		if r.Error != "" {
+			response.Failed++
+		}
+		response.Results = append(response.Results, &pb.QueueBatchResult{Name: r.Name, Error: r.Error, Status: r.Status})
+	}
+	return response, nil
+}
+
+// PublishBatch push many messages to subscribers at once by using gRPC.
+func (s *Server) PublishBatch(ctx context.Context, in *pb.PublishBatchRequest) (*pb.PublishBatchResponse, error) {
+	items := make([]*BatchItemModel, 0, len(in.Items))
+	for _, i := range in.Items {
+		items = append(items, &BatchItemModel{Name: i.Name, Body: i.Message, Headers: i.Headers})
+	}
+	results, err := s.sendBatchGRPC(ctx, items, true)
+	if err != nil {
+		return nil, err
+	}
+	response := &pb.PublishBatchResponse{}
+	for _, r := range results {
+		if r.Error != "" {
+			response.Failed++
+		}
+		response.Results = append(response.Results, &pb.PublishBatchResult{Name: r.Name, Error: r.Error, Status: r.Status})
+	}
+	return response, nil
+}
+
+func (s *Server) sendBatchREST(ctx *fasthttp.RequestCtx, pubSub bool) {
+	var request BatchRequestModel
+	if err := json.Unmarshal(ctx.PostBody(), &request); err != nil {
+		s.badRequest(ctx, fmt.Sprintf("the request body is not a valid batch. %v", err))
+		return
+	}
+	if err := validateBatch(request.Messages); err != nil {
+		s.badRequest(ctx, err.Error())
+		return
+	}
+
+	var service string
+	if s.mainAPI != "" {
+		service = s.mainAPI
+	} else {
+		service = string(ctx.Request.Header.Peek("x-service-name"))
+	}
+
+	proxied := map[string][]byte{}
+	if config.Cfg.Proxy != nil {
+		for _, v := range config.Cfg.Proxy.Headers {
+			proxied[v] = ctx.Request.Header.Peek(v)
+		}
+	}
+
+	results, err := s.sendBatch(service, request.Messages, proxied, pubSub)
+	if err != nil {
+		s.error(ctx, fasthttp.StatusInternalServerError, err.Error())
+		return
+	}
+	response := &BatchResponseModel{Results: results}
+	for _, r := range results {
+		if r.Error != "" {
+			response.Failed++
+		}
+	}
+	s.write(ctx, fasthttp.StatusOK, response)
+}
+
+func (s *Server) sendBatchGRPC(ctx context.Context, items []*BatchItemModel, pubSub bool) ([]*BatchResultModel, error) {
+	if err := validateBatch(items); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	md, mdOk := metadata.FromIncomingContext(ctx)
+
+	var service string
+	if s.mainAPI != "" {
+		service = s.mainAPI
+	} else if mdOk {
+		if h := md.Get("x-service-name"); len(h) > 0 {
+			service = h[0]
+		}
+	}
+
+	proxied := map[string][]byte{}
+	if mdOk && config.Cfg.Proxy != nil {
+		for _, v := range config.Cfg.Proxy.Headers {
+			if h := md.Get(v); len(h) > 0 {
+				proxied[v] = []byte(h[0])
+			}
+		}
+	}
+
+	results, err := s.sendBatch(service, items, proxied, pubSub)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return results, nil
+}
+
+// sendBatch wraps the messages and sends them at once if the broker supports, one by one otherwise.
+func (s *Server) sendBatch(service string, items []*BatchItemModel, proxied map[string][]byte, pubSub bool) ([]*BatchResultModel, error) {
+	messages := make([]*messaging.BatchMessage, 0, len(items))
+	for _, i := range items {
+		headers := make(map[string][]byte, len(proxied)+len(i.Headers))
+		for k, v := range proxied {
+			headers[k] = v
+		}
+		for k, v := range i.Headers {
+			headers[k] = []byte(v)
+		}
+		message, err := s.wrapper.Wrap(i.Body, headers)
+		if err != nil {
+			return nil, errors.New("failed to wrap message.")
+		}
+		messages = append(messages, &messaging.BatchMessage{Name: i.Name, Message: message})
+	}
+
+	var errs []error
+	if sender, ok := s.messager.(messaging.BatchSender); ok {
+		if pubSub {
+			errs = sender.PublishBatch(service, messages)
+		} else {
+			errs = sender.QueueBatch(service, messages)
+		}
+	} else {
+		errs = make([]error, len(messages))
+		for n, m := range messages {
+			if pubSub {
+				errs[n] = s.messager.Publish(service, m.Name, m.Message)
+			} else {
+				errs[n] = s.messager.Queue(service, m.Name, m.Message)
+			}
+		}
+	}
+
+	results := make([]*BatchResultModel, 0, len(messages))
+	for n, m := range messages {
+		r := &BatchResultModel{Name: m.Name}
+		if sent, err := sendStatus(m.Name, pubSub, errs[n]); err != nil {
+			r.Error = err.Error()
+		} else {
+			r.Status = sent
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func validateBatch(items []*BatchItemModel) error {
+	if len(items) == 0 {
+		return errors.New("at least one message is required.")
+	}
+	for n, i := range items {
+		if i == nil || i.Name == "" {
+			return fmt.Errorf("the \"name\" of the message %d is required.", n)
+		}
+		if len(i.Body) == 0 {
+			return fmt.Errorf("the body of the message %d is required.", n)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/messaging"
+	"github.com/valyala/fasthttp"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// StatusConfirmed is the status of a message the broker took the responsibility of.
+	StatusConfirmed = "confirmed"
+	// StatusUnroutable is the status of a message that reached no queue, an event without subscribers or a job
+	// without a worker.
+	StatusUnroutable = "unroutable"
+)
+
+// SendResponseModel is returned once a message is queued or published.
+type SendResponseModel struct {
+	Status string `json:"status"`
+	// ID is the ID of a queued job.
+	ID string `json:"id,omitempty"`
+}
+
+// sendStatus returns the status of a sent message. A message that reached no queue is not a failure unless it is an
+// event that rejects such messages.
+func sendStatus(name string, pubSub bool, err error) (string, error) {
+	if err == nil {
+		return StatusConfirmed, nil
+	}
+	if err == messaging.ErrUnroutable && !(pubSub && rejectUnroutable(name)) {
+		return StatusUnroutable, nil
+	}
+	return "", err
+}
+
+func rejectUnroutable(name string) bool {
+	for _, e := range config.Cfg.Events {
+		if e.Name == name {
+			return e.RejectUnroutable
+		}
+	}
+	return false
+}
+
+// sendErrorREST returns the status code of a failed send.
+func sendErrorREST(err error) int {
+	switch err {
+	case errScheduleNotSupported:
+		return fasthttp.StatusNotImplemented
+	case messaging.ErrUnroutable:
+		return fasthttp.StatusUnprocessableEntity
+	case messaging.ErrNacked:
+		return fasthttp.StatusServiceUnavailable
+	}
+	return fasthttp.StatusInternalServerError
+}
+
+// sendErrorGRPC returns the status code of a failed send.
+func sendErrorGRPC(err error) codes.Code {
+	switch err {
+	case errScheduleNotSupported:
+		return codes.Unimplemented
+	case messaging.ErrUnroutable:
+		return codes.FailedPrecondition
+	case messaging.ErrNacked:
+		return codes.Unavailable
+	}
+	return codes.Unknown
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/turgayozgur/messageman/internal/messaging"
+	pb "github.com/turgayozgur/messageman/pb/v1/gen"
+	"github.com/valyala/fasthttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DefaultPeekLimit is the number of dead letters peeked if no limit given.
+const DefaultPeekLimit = 10
+
+// DeadLetterQueueModel .
+type DeadLetterQueueModel struct {
+	Queue string `json:"queue"`
+	Count int64  `json:"count"`
+}
+
+// DeadLetterModel is a dead letter with its unwrapped body and headers.
+type DeadLetterModel struct {
+	ID         string            `json:"id"`
+	Service    string            `json:"service"`
+	Name       string            `json:"name"`
+	Queue      string            `json:"queue"`
+	Body       string            `json:"body"`
+	Headers    map[string]string `json:"headers"`
+	Attempt    int               `json:"attempt"`
+	StatusCode int               `json:"statusCode"`
+	Error      string            `json:"error"`
+	QueuedAt   *time.Time        `json:"queuedAt,omitempty"`
+	FailedAt   time.Time         `json:"failedAt"`
+}
+
+// PurgeModel .
+type PurgeModel struct {
+	Purged int64 `json:"purged"`
+}
+
+// DeadLetterQueuesREST lists the dead letter queues that have messages.
+func (s *Server) DeadLetterQueuesREST(ctx *fasthttp.RequestCtx) {
+	d, ok := s.deadLetterer()
+	if !ok {
+		s.error(ctx, fasthttp.StatusNotImplemented, "dead letters are not supported by the broker.")
+		return
+	}
+	queues, err := d.DeadLetterQueues()
+	if err != nil {
+		s.error(ctx, fasthttp.StatusInternalServerError, err.Error())
+		return
+	}
+	models := make([]*DeadLetterQueueModel, 0, len(queues))
+	for _, q := range queues {
+		models = append(models, &DeadLetterQueueModel{Queue: q.Queue, Count: q.Count})
+	}
+	s.write(ctx, fasthttp.StatusOK, models)
+}
+
+// PeekDeadLettersREST returns the oldest dead letters of a queue without removing them.
+func (s *Server) PeekDeadLettersREST(ctx *fasthttp.RequestCtx) {
+	queue := string(ctx.QueryArgs().Peek("queue"))
+	if queue == "" {
+		s.badRequest(ctx, "\"queue\" parameter is required.")
+		return
+	}
+	limit := DefaultPeekLimit
+	if v := ctx.QueryArgs().Peek("limit"); len(v) > 0 {
+		var err error
+		if limit, err = strconv.Atoi(string(v)); err != nil || limit < 1 {
+			s.badRequest(ctx, "\"limit\" parameter must be a positive number.")
+			return
+		}
+	}
+	d, ok := s.deadLetterer()
+	if !ok {
+		s.error(ctx, fasthttp.StatusNotImplemented, "dead letters are not supported by the broker.")
+		return
+	}
+	letters, err := d.PeekDeadLetters(queue, limit)
+	if err != nil {
+		s.error(ctx, fasthttp.StatusInternalServerError, err.Error())
+		return
+	}
+	models := make([]*DeadLetterModel, 0, len(letters))
+	for _, l := range letters {
+		models = append(models, s.deadLetterModel(l))
+	}
+	s.write(ctx, fasthttp.StatusOK, models)
+}
+
+// ReplayDeadLetterREST sends a dead letter back to its consumer. The request body, if any, replaces the message.
+func (s *Server) ReplayDeadLetterREST(ctx *fasthttp.RequestCtx) {
+	if !s.allowMethod(ctx, fasthttp.MethodPost) {
+		return
+	}
+	queue := string(ctx.QueryArgs().Peek("queue"))
+	id := string(ctx.QueryArgs().Peek("id"))
+	if queue == "" || id == "" {
+		s.badRequest(ctx, "\"queue\" and \"id\" parameters are required.")
+		return
+	}
+	d, ok := s.deadLetterer()
+	if !ok {
+		s.error(ctx, fasthttp.StatusNotImplemented, "dead letters are not supported by the broker.")
+		return
+	}
+	var message []byte
+	if body := ctx.PostBody(); len(body) > 0 {
+		var err error
+		if message, err = s.wrapBodyREST(ctx, body); err != nil {
+			s.error(ctx, fasthttp.StatusInternalServerError, "failed to wrap message.")
+			return
+		}
+	}
+	if err := d.ReplayDeadLetter(queue, id, message); err != nil {
+		if err == messaging.ErrDeadLetterNotFound {
+			s.error(ctx, fasthttp.StatusNotFound, err.Error())
+			return
+		}
+		s.error(ctx, fasthttp.StatusInternalServerError, err.Error())
+		return
+	}
+	s.write(ctx, fasthttp.StatusOK, nil)
+}
+
+// PurgeDeadLettersREST removes all dead letters of a queue.
+func (s *Server) PurgeDeadLettersREST(ctx *fasthttp.RequestCtx) {
+	if !s.allowMethod(ctx, fasthttp.MethodPost, fasthttp.MethodDelete) {
+		return
+	}
+	queue := string(ctx.QueryArgs().Peek("queue"))
+	if queue == "" {
+		s.badRequest(ctx, "\"queue\" parameter is required.")
+		return
+	}
+	d, ok := s.deadLetterer()
+	if !ok {
+		s.error(ctx, fasthttp.StatusNotImplemented, "dead letters are not supported by the broker.")
+		return
+	}
+	n, err := d.PurgeDeadLetters(queue)
+	if err != nil {
+		s.error(ctx, fasthttp.StatusInternalServerError, err.Error())
+		return
+	}
+	s.write(ctx, fasthttp.StatusOK, &PurgeModel{Purged: n})
+}
+
+// ListDeadLetterQueues lists the dead letter queues that have messages by using gRPC.
+func (s *Server) ListDeadLetterQueues(ctx context.Context, in *empty.Empty) (*pb.ListDeadLetterQueuesResponse, error) {
+	d, ok := s.deadLetterer()
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "dead letters are not supported by the broker.")
+	}
+	queues, err := d.DeadLetterQueues()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, err.Error())
+	}
+	response := &pb.ListDeadLetterQueuesResponse{}
+	for _, q := range queues {
+		response.Queues = append(response.Queues, &pb.DeadLetterQueue{Queue: q.Queue, Count: q.Count})
+	}
+	return response, nil
+}
+
+// PeekDeadLetters returns the oldest dead letters of a queue without removing them by using gRPC.
+func (s *Server) PeekDeadLetters(ctx context.Context, in *pb.PeekDeadLettersRequest) (*pb.PeekDeadLettersResponse, error) {
+	if in.Queue == "" {
+		return nil, status.Error(codes.InvalidArgument, "the \"queue\" field is required.")
+	}
+	limit := int(in.Limit)
+	if limit < 1 {
+		limit = DefaultPeekLimit
+	}
+	d, ok := s.deadLetterer()
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "dead letters are not supported by the broker.")
+	}
+	letters, err := d.PeekDeadLetters(in.Queue, limit)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, err.Error())
+	}
+	response := &pb.PeekDeadLettersResponse{}
+	for _, l := range letters {
+		m := s.deadLetterModel(l)
+		letter := &pb.DeadLetter{
+			Id:         m.ID,
+			Service:    m.Service,
+			Name:       m.Name,
+			Queue:      m.Queue,
+			Message:    []byte(m.Body),
+			Headers:    m.Headers,
+			Attempt:    int32(m.Attempt),
+			StatusCode: int32(m.StatusCode),
+			Error:      m.Error,
+			FailedAt:   timestamppb.New(m.FailedAt),
+		}
+		if m.QueuedAt != nil {
+			letter.QueuedAt = timestamppb.New(*m.QueuedAt)
+		}
+		response.DeadLetters = append(response.DeadLetters, letter)
+	}
+	return response, nil
+}
+
+// ReplayDeadLetter sends a dead letter back to its consumer by using gRPC. The message, if any, replaces the original one.
+func (s *Server) ReplayDeadLetter(ctx context.Context, in *pb.ReplayDeadLetterRequest) (*empty.Empty, error) {
+	if in.Queue == "" || in.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "the \"queue\" and \"id\" fields are required.")
+	}
+	d, ok := s.deadLetterer()
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "dead letters are not supported by the broker.")
+	}
+	var message []byte
+	if len(in.Message) > 0 {
+		md, mdOk := metadata.FromIncomingContext(ctx)
+		var err error
+		if message, err = s.wrapBodyGRPC(mdOk, md, in.Message); err != nil {
+			return nil, status.Error(codes.Internal, "failed to wrap message.")
+		}
+	}
+	if err := d.ReplayDeadLetter(in.Queue, in.Id, message); err != nil {
+		if err == messaging.ErrDeadLetterNotFound {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.Unknown, err.Error())
+	}
+	return &empty.Empty{}, nil
+}
+
+// PurgeDeadLetters removes all dead letters of a queue by using gRPC.
+func (s *Server) PurgeDeadLetters(ctx context.Context, in *pb.PurgeDeadLettersRequest) (*pb.PurgeDeadLettersResponse, error) {
+	if in.Queue == "" {
+		return nil, status.Error(codes.InvalidArgument, "the \"queue\" field is required.")
+	}
+	d, ok := s.deadLetterer()
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "dead letters are not supported by the broker.")
+	}
+	n, err := d.PurgeDeadLetters(in.Queue)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, err.Error())
+	}
+	return &pb.PurgeDeadLettersResponse{Purged: n}, nil
+}
+
+func (s *Server) deadLetterer() (messaging.DeadLetterer, bool) {
+	d, ok := s.messager.(messaging.DeadLetterer)
+	return d, ok
+}
+
+// deadLetterModel unwraps the stored message. A message that can not be unwrapped is returned as it is.
+func (s *Server) deadLetterModel(l *messaging.DeadLetter) *DeadLetterModel {
+	m := &DeadLetterModel{
+		ID:         l.ID,
+		Service:    l.Service,
+		Name:       l.Name,
+		Queue:      l.Queue,
+		Body:       string(l.Body),
+		Headers:    map[string]string{},
+		Attempt:    l.Attempt,
+		StatusCode: l.StatusCode,
+		Error:      l.Error,
+		FailedAt:   l.FailedAt,
+	}
+	if !l.QueuedAt.IsZero() {
+		m.QueuedAt = &l.QueuedAt
+	}
+	if body, headers, err := s.wrapper.Unwrap(l.Body); err == nil {
+		m.Body = string(body)
+		for k, v := range headers {
+			m.Headers[k] = string(v)
+		}
+	}
+	return m
+}
@@ -2,15 +2,37 @@ package service
 
 import (
 	"context"
-	"github.com/golang/protobuf/ptypes/empty"
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
 	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/jobs"
+	"github.com/turgayozgur/messageman/internal/messaging"
 	pb "github.com/turgayozgur/messageman/pb/v1/gen"
 	"github.com/valyala/fasthttp"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+var errJobsNotTracked = errors.New("the statuses of the jobs are not tracked. A job store is required.")
+
+// JobModel is the last known status of a queued job.
+type JobModel struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Attempt   int       `json:"attempt"`
+	Error     string    `json:"error,omitempty"`
+	QueuedAt  time.Time `json:"queuedAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
 // QueueREST push message to workers.
 func (s *Server) QueueREST(ctx *fasthttp.RequestCtx) {
 	queueName := string(ctx.QueryArgs().Peek("name"))
@@ -26,6 +48,18 @@ func (s *Server) QueueREST(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	at, err := s.deliverAtREST(ctx)
+	if err != nil {
+		s.badRequest(ctx, err.Error())
+		return
+	}
+
+	callback, err := callbackHeaders(string(ctx.QueryArgs().Peek("callback_url")), string(ctx.QueryArgs().Peek("callback_event")))
+	if err != nil {
+		s.badRequest(ctx, err.Error())
+		return
+	}
+
 	var service string
 	if s.mainAPI != "" {
 		service = s.mainAPI
@@ -34,21 +68,27 @@ func (s *Server) QueueREST(ctx *fasthttp.RequestCtx) {
 		service = string(ctx.Request.Header.Peek("x-service-name"))
 	}
 
-	var err error
 	if body, err = s.wrapBodyREST(ctx, body); err != nil {
 		s.error(ctx, fasthttp.StatusInternalServerError, "failed to wrap message.")
+		return
 	}
 
-	if err = s.messager.Queue(service, queueName, body); err != nil {
-		s.error(ctx, fasthttp.StatusInternalServerError, err.Error())
+	id := messaging.NewJobID()
+	sent, err := sendStatus(queueName, false, s.queueJob(service, queueName, id, body, callback, at))
+	if err != nil {
+		s.error(ctx, sendErrorREST(err), err.Error())
 		return
 	}
 
-	s.write(ctx, fasthttp.StatusOK, nil)
+	// an unroutable job is not tracked.
+	if sent != StatusConfirmed {
+		id = ""
+	}
+	s.write(ctx, fasthttp.StatusOK, &SendResponseModel{Status: sent, ID: id})
 }
 
 // Queue push message to workers by using gRPC.
-func (s *Server) Queue(ctx context.Context, in *pb.QueueRequest) (*empty.Empty, error) {
+func (s *Server) Queue(ctx context.Context, in *pb.QueueRequest) (*pb.QueueResponse, error) {
 	queueName := in.Name
 
 	if in.Name == "" {
@@ -60,6 +100,16 @@ func (s *Server) Queue(ctx context.Context, in *pb.QueueRequest) (*empty.Empty,
 		return nil, status.Error(codes.InvalidArgument, "the \"message\" field is required.")
 	}
 
+	at, err := s.deliverAtGRPC(in.DelayMs, in.DeliverAt)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	callback, err := callbackHeaders(in.CallbackUrl, in.CallbackEvent)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	md, mdOk := metadata.FromIncomingContext(ctx)
 
 	var service string
@@ -73,18 +123,199 @@ func (s *Server) Queue(ctx context.Context, in *pb.QueueRequest) (*empty.Empty,
 		}
 	}
 
-	var err error
-	if mdOk {
-		if body, err = s.wrapBodyGRPC(mdOk, md, body); err != nil {
-			return nil, status.Error(codes.Internal, "failed to wrap message.")
+	// the job ID is a header, so the message is wrapped even without the metadata.
+	if body, err = s.wrapBodyGRPC(mdOk, md, body); err != nil {
+		return nil, status.Error(codes.Internal, "failed to wrap message.")
+	}
+
+	id := messaging.NewJobID()
+	sent, err := sendStatus(queueName, false, s.queueJob(service, queueName, id, body, callback, at))
+	if err != nil {
+		return nil, status.Error(sendErrorGRPC(err), err.Error())
+	}
+
+	if sent != StatusConfirmed {
+		id = ""
+	}
+	return &pb.QueueResponse{Status: sent, Id: id}, nil
+}
+
+// GetJobREST returns the last known status of a queued job.
+func (s *Server) GetJobREST(ctx *fasthttp.RequestCtx, id string) {
+	if s.jobs == nil {
+		s.error(ctx, fasthttp.StatusNotImplemented, errJobsNotTracked.Error())
+		return
+	}
+	job, err := s.jobs.Get(id)
+	if err != nil {
+		s.error(ctx, jobErrorREST(err), err.Error())
+		return
+	}
+	s.write(ctx, fasthttp.StatusOK, jobModel(job))
+}
+
+// GetJob returns the last known status of a queued job by using gRPC.
+func (s *Server) GetJob(ctx context.Context, in *pb.GetJobRequest) (*pb.Job, error) {
+	if in.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "the \"id\" field is required.")
+	}
+	if s.jobs == nil {
+		return nil, status.Error(codes.Unimplemented, errJobsNotTracked.Error())
+	}
+	job, err := s.jobs.Get(in.Id)
+	if err != nil {
+		return nil, status.Error(jobErrorGRPC(err), err.Error())
+	}
+	return jobMessage(job), nil
+}
+
+// CancelJobREST revokes a queued or a retrying job.
+func (s *Server) CancelJobREST(ctx *fasthttp.RequestCtx, id string) {
+	if s.jobs == nil {
+		s.error(ctx, fasthttp.StatusNotImplemented, errJobsNotTracked.Error())
+		return
+	}
+	job, err := jobs.Revoke(s.jobs, id)
+	if err != nil {
+		s.error(ctx, jobErrorREST(err), err.Error())
+		return
+	}
+	s.write(ctx, fasthttp.StatusOK, jobModel(job))
+}
+
+// CancelJob revokes a queued or a retrying job by using gRPC.
+func (s *Server) CancelJob(ctx context.Context, in *pb.CancelJobRequest) (*pb.Job, error) {
+	if in.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "the \"id\" field is required.")
+	}
+	if s.jobs == nil {
+		return nil, status.Error(codes.Unimplemented, errJobsNotTracked.Error())
+	}
+	job, err := jobs.Revoke(s.jobs, in.Id)
+	if err != nil {
+		return nil, status.Error(jobErrorGRPC(err), err.Error())
+	}
+	return jobMessage(job), nil
+}
+
+func jobModel(job *jobs.Job) *JobModel {
+	return &JobModel{
+		ID:        job.ID,
+		Name:      job.Name,
+		Status:    job.Status,
+		Attempt:   job.Attempt,
+		Error:     job.Error,
+		QueuedAt:  job.QueuedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+}
+
+func jobMessage(job *jobs.Job) *pb.Job {
+	return &pb.Job{
+		Id:        job.ID,
+		Name:      job.Name,
+		Status:    job.Status,
+		Attempt:   int32(job.Attempt),
+		Error:     job.Error,
+		QueuedAt:  timestamppb.New(job.QueuedAt),
+		UpdatedAt: timestamppb.New(job.UpdatedAt),
+	}
+}
+
+// jobErrorREST returns the status code of a failed job lookup or revoke.
+func jobErrorREST(err error) int {
+	switch err {
+	case jobs.ErrNotFound:
+		return fasthttp.StatusNotFound
+	case jobs.ErrNotRevocable:
+		return fasthttp.StatusConflict
+	}
+	return fasthttp.StatusInternalServerError
+}
+
+// jobErrorGRPC returns the status code of a failed job lookup or revoke.
+func jobErrorGRPC(err error) codes.Code {
+	switch err {
+	case jobs.ErrNotFound:
+		return codes.NotFound
+	case jobs.ErrNotRevocable:
+		return codes.FailedPrecondition
+	}
+	return codes.Unknown
+}
+
+// callbackHeaders returns the headers the worker sends the result of the job with. The URL must be absolute and
+// allowed, messageman posts to it from inside the network.
+func callbackHeaders(callbackURL string, event string) (map[string][]byte, error) {
+	headers := map[string][]byte{}
+	if callbackURL != "" {
+		u, err := url.Parse(callbackURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+			return nil, errors.New("the callback url must be an absolute http or https url.")
 		}
+		if !allowedCallback(u) {
+			return nil, errors.New("the callback url is not allowed.")
+		}
+		headers[messaging.CallbackURLHeader] = []byte(callbackURL)
+	}
+	if event != "" {
+		headers[messaging.CallbackEventHeader] = []byte(event)
 	}
+	return headers, nil
+}
 
-	if err = s.messager.Queue(service, queueName, body); err != nil {
-		return nil, status.Error(codes.Unknown, err.Error())
+// allowedCallback returns true if the callback url has the scheme and the host of an allowed url, and its path is
+// under the path of it. No url is allowed if none is configured.
+func allowedCallback(u *url.URL) bool {
+	if config.Cfg.Callbacks == nil {
+		return false
+	}
+	// the dot segments are resolved like the receiving server does.
+	p := path.Clean("/" + u.Path)
+	for _, v := range config.Cfg.Callbacks.AllowedURLs {
+		allowed, err := url.Parse(v)
+		if err != nil || allowed.Scheme != u.Scheme || !strings.EqualFold(allowed.Host, u.Host) {
+			continue
+		}
+		prefix := strings.TrimSuffix(allowed.Path, "/")
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
 	}
+	return false
+}
 
-	return &empty.Empty{}, nil
+// queueJob saves the job as queued and queues its message with the job ID and the extra headers. The job is
+// removed if the message is not queued, the caller gets no ID to look it up.
+func (s *Server) queueJob(service string, name string, id string, message []byte, extra map[string][]byte, at time.Time) error {
+	body, headers, err := s.wrapper.Unwrap(message)
+	if err != nil {
+		return err
+	}
+	if headers == nil {
+		headers = map[string][]byte{}
+	}
+	for k, v := range extra {
+		headers[k] = v
+	}
+	headers[messaging.JobIDHeader] = []byte(id)
+	if message, err = s.wrapper.Wrap(body, headers); err != nil {
+		return err
+	}
+	if s.jobs == nil {
+		return s.queue(service, name, message, at)
+	}
+	now := time.Now()
+	if err := s.jobs.Save(&jobs.Job{ID: id, Name: name, Status: jobs.Queued, QueuedAt: now, UpdatedAt: now}); err != nil {
+		log.Error().Err(err).Str("id", id).Str("name", name).Msg("failed to save the job")
+	}
+	if err := s.queue(service, name, message, at); err != nil {
+		if err := s.jobs.Delete(id); err != nil {
+			log.Error().Err(err).Str("id", id).Str("name", name).Msg("failed to remove the job")
+		}
+		return err
+	}
+	return nil
 }
 
 func (s *Server) wrapBodyREST(ctx *fasthttp.RequestCtx, body []byte) ([]byte, error) {
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/messaging"
+)
+
+func TestCallbackHeaders(t *testing.T) {
+	defer func(c *config.CallbacksConfig) { config.Cfg.Callbacks = c }(config.Cfg.Callbacks)
+
+	config.Cfg.Callbacks = nil
+	if _, err := callbackHeaders("http://reportapi/api/exports/done", ""); err == nil {
+		t.Error("a callback url is allowed without the allowed urls")
+	}
+	if _, err := callbackHeaders("", "report_exported"); err != nil {
+		t.Errorf("the callback event is rejected. %v", err)
+	}
+
+	config.Cfg.Callbacks = &config.CallbacksConfig{AllowedURLs: []string{"http://reportapi/api/exports/", "https://hooks.example.com"}}
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"http://reportapi/api/exports/done", true},
+		{"http://reportapi/api/exports", true},
+		{"http://REPORTAPI/api/exports/done?id=1", true},
+		{"https://hooks.example.com/any/path", true},
+		{"https://hooks.example.com", true},
+		{"https://reportapi/api/exports/done", false},
+		{"http://reportapi/api/exportsx", false},
+		{"http://reportapi/api/exports/../../admin", false},
+		{"http://reportapi/api/other", false},
+		{"http://reportapi:8080/api/exports/done", false},
+		{"http://reportapi.evil.com/api/exports/done", false},
+		{"http://reportapi@evil.com/api/exports/done", false},
+		{"https://hooks.example.com.evil.com/", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"file:///etc/passwd", false},
+		{"/api/exports/done", false},
+	}
+	for _, tt := range tests {
+		headers, err := callbackHeaders(tt.url, "")
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("%s: allowed %v, want %v. %v", tt.url, allowed, tt.allowed, err)
+			continue
+		}
+		if tt.allowed && string(headers[messaging.CallbackURLHeader]) != tt.url {
+			t.Errorf("%s: the header is %q", tt.url, headers[messaging.CallbackURLHeader])
+		}
+	}
+}
@@ -2,7 +2,6 @@ package service
 
 import (
 	"context"
-	"github.com/golang/protobuf/ptypes/empty"
 	pb "github.com/turgayozgur/messageman/pb/v1/gen"
 	"github.com/valyala/fasthttp"
 	"google.golang.org/grpc/codes"
@@ -25,6 +24,12 @@ func (s *Server) PublishREST(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	at, err := s.deliverAtREST(ctx)
+	if err != nil {
+		s.badRequest(ctx, err.Error())
+		return
+	}
+
 	var publisher string
 	if s.mainAPI != "" {
 		publisher = s.mainAPI
@@ -33,22 +38,22 @@ func (s *Server) PublishREST(ctx *fasthttp.RequestCtx) {
 		publisher = string(ctx.Request.Header.Peek("x-service-name"))
 	}
 
-	var err error
 	if body, err = s.wrapBodyREST(ctx, body); err != nil {
 		s.error(ctx, fasthttp.StatusInternalServerError, "failed to wrap message.")
+		return
 	}
 
-	err = s.messager.Publish(publisher, eventName, body)
+	sent, err := sendStatus(eventName, true, s.publish(publisher, eventName, body, at))
 	if err != nil {
-		s.error(ctx, fasthttp.StatusInternalServerError, err.Error())
+		s.error(ctx, sendErrorREST(err), err.Error())
 		return
 	}
 
-	s.write(ctx, fasthttp.StatusOK, nil)
+	s.write(ctx, fasthttp.StatusOK, &SendResponseModel{Status: sent})
 }
 
 // Publish push message to subscribers by using gRPC.
-func (s *Server) Publish(ctx context.Context, in *pb.PublishRequest) (*empty.Empty, error) {
+func (s *Server) Publish(ctx context.Context, in *pb.PublishRequest) (*pb.PublishResponse, error) {
 	eventName := in.Name
 
 	if in.Name == "" {
@@ -60,6 +65,11 @@ func (s *Server) Publish(ctx context.Context, in *pb.PublishRequest) (*empty.Emp
 		return nil, status.Error(codes.InvalidArgument, "The \"message\" field is required.")
 	}
 
+	at, err := s.deliverAtGRPC(in.DelayMs, in.DeliverAt)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	md, mdOk := metadata.FromIncomingContext(ctx)
 
 	var publisher string
@@ -73,16 +83,16 @@ func (s *Server) Publish(ctx context.Context, in *pb.PublishRequest) (*empty.Emp
 		}
 	}
 
-	var err error
 	if mdOk {
 		if body, err = s.wrapBodyGRPC(mdOk, md, body); err != nil {
 			return nil, status.Error(codes.Internal, "failed to wrap message.")
 		}
 	}
 
-	if err = s.messager.Publish(publisher, eventName, body); err != nil {
-		return nil, status.Error(codes.Unknown, err.Error())
+	sent, err := sendStatus(eventName, true, s.publish(publisher, eventName, body, at))
+	if err != nil {
+		return nil, status.Error(sendErrorGRPC(err), err.Error())
 	}
 
-	return &empty.Empty{}, nil
+	return &pb.PublishResponse{Status: sent}, nil
 }
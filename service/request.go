@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/messaging"
+	pb "github.com/turgayozgur/messageman/pb/v1/gen"
+	"github.com/valyala/fasthttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestREST queues the message and responds with the response of the worker. The status code of the worker is
+// returned with the response, 504 if the worker does not respond in time.
+func (s *Server) RequestREST(ctx *fasthttp.RequestCtx) {
+	queueName := string(ctx.QueryArgs().Peek("name"))
+
+	if queueName == "" {
+		s.badRequest(ctx, "\"name\" parameter is required.")
+		return
+	}
+
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		s.badRequest(ctx, "the request body is required.")
+		return
+	}
+
+	timeout := messaging.DefaultRequestTimeout
+	if v := ctx.QueryArgs().Peek("timeout_ms"); len(v) > 0 {
+		ms, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil || ms < 1 {
+			s.badRequest(ctx, "\"timeout_ms\" parameter must be a positive number.")
+			return
+		}
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+
+	var service string
+	if s.mainAPI != "" {
+		service = s.mainAPI
+	} else {
+		service = string(ctx.Request.Header.Peek("x-service-name"))
+	}
+
+	headers := map[string][]byte{}
+	if config.Cfg.Proxy != nil {
+		for _, v := range config.Cfg.Proxy.Headers {
+			headers[v] = ctx.Request.Header.Peek(v)
+		}
+	}
+
+	reply, err := s.requester.Request(service, queueName, body, headers, timeout)
+	if err != nil {
+		if err == messaging.ErrRequestTimeout {
+			s.error(ctx, fasthttp.StatusGatewayTimeout, err.Error())
+			return
+		}
+		if err == messaging.ErrRequestNotSupported {
+			s.error(ctx, fasthttp.StatusNotImplemented, err.Error())
+			return
+		}
+		s.error(ctx, fasthttp.StatusInternalServerError, err.Error())
+		return
+	}
+	if reply.Error != "" {
+		statusCode := reply.StatusCode
+		if statusCode < 400 {
+			// no response or a gRPC status code.
+			statusCode = fasthttp.StatusBadGateway
+		}
+		s.error(ctx, statusCode, reply.Error)
+		return
+	}
+	ctx.Response.Header.SetContentType(messaging.ContentType)
+	if reply.StatusCode >= 200 && reply.StatusCode < 300 {
+		ctx.Response.SetStatusCode(reply.StatusCode)
+	}
+	ctx.Response.SetBody(reply.Body)
+}
+
+// Request queues the message and returns the response of the worker by using gRPC.
+func (s *Server) Request(ctx context.Context, in *pb.RequestRequest) (*pb.RequestResponse, error) {
+	if in.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "the \"name\" field is required.")
+	}
+	if len(in.Message) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "the \"message\" field is required.")
+	}
+	if in.TimeoutMs < 0 {
+		return nil, status.Error(codes.InvalidArgument, "the \"timeout_ms\" field must be a positive number.")
+	}
+	timeout := messaging.DefaultRequestTimeout
+	if in.TimeoutMs > 0 {
+		timeout = time.Duration(in.TimeoutMs) * time.Millisecond
+	}
+
+	md, mdOk := metadata.FromIncomingContext(ctx)
+
+	var service string
+	if s.mainAPI != "" {
+		service = s.mainAPI
+	} else if mdOk {
+		if h := md.Get("x-service-name"); len(h) > 0 {
+			service = h[0]
+		}
+	}
+
+	headers := map[string][]byte{}
+	if mdOk && config.Cfg.Proxy != nil {
+		for _, v := range config.Cfg.Proxy.Headers {
+			if h := md.Get(v); len(h) > 0 {
+				headers[v] = []byte(h[0])
+			}
+		}
+	}
+
+	reply, err := s.requester.Request(service, in.Name, in.Message, headers, timeout)
+	if err != nil {
+		if err == messaging.ErrRequestTimeout {
+			return nil, status.Error(codes.DeadlineExceeded, err.Error())
+		}
+		if err == messaging.ErrRequestNotSupported {
+			return nil, status.Error(codes.Unimplemented, err.Error())
+		}
+		return nil, status.Error(codes.Unknown, err.Error())
+	}
+	return &pb.RequestResponse{Message: reply.Body, StatusCode: int32(reply.StatusCode), Error: reply.Error}, nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/turgayozgur/messageman/internal/messaging"
+	"github.com/valyala/fasthttp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	errScheduleNotSupported = errors.New("delayed delivery is not supported by the broker.")
+	errDelayAndDeliverAt    = errors.New("only one of the delay and the deliver at can be given.")
+)
+
+// deliverAtREST reads the "delay_ms" or the "deliver_at" (RFC 3339) parameter. Zero time if none given.
+func (s *Server) deliverAtREST(ctx *fasthttp.RequestCtx) (time.Time, error) {
+	delay := ctx.QueryArgs().Peek("delay_ms")
+	deliverAt := ctx.QueryArgs().Peek("deliver_at")
+	if len(delay) > 0 && len(deliverAt) > 0 {
+		return time.Time{}, errDelayAndDeliverAt
+	}
+	if len(delay) > 0 {
+		ms, err := strconv.ParseInt(string(delay), 10, 64)
+		if err != nil || ms < 0 {
+			return time.Time{}, errors.New("\"delay_ms\" parameter must be a positive number.")
+		}
+		return time.Now().Add(time.Duration(ms) * time.Millisecond), nil
+	}
+	if len(deliverAt) > 0 {
+		at, err := time.Parse(time.RFC3339, string(deliverAt))
+		if err != nil {
+			return time.Time{}, errors.New("\"deliver_at\" parameter must be an RFC 3339 time.")
+		}
+		return at, nil
+	}
+	return time.Time{}, nil
+}
+
+// deliverAtGRPC reads the "delay_ms" or the "deliver_at" field. Zero time if none given.
+func (s *Server) deliverAtGRPC(delay int64, deliverAt *timestamppb.Timestamp) (time.Time, error) {
+	if delay != 0 && deliverAt != nil {
+		return time.Time{}, errDelayAndDeliverAt
+	}
+	if delay < 0 {
+		return time.Time{}, errors.New("the \"delay_ms\" field must be a positive number.")
+	}
+	if delay > 0 {
+		return time.Now().Add(time.Duration(delay) * time.Millisecond), nil
+	}
+	if deliverAt != nil {
+		if err := deliverAt.CheckValid(); err != nil {
+			return time.Time{}, err
+		}
+		return deliverAt.AsTime(), nil
+	}
+	return time.Time{}, nil
+}
+
+// queue holds the message back if it is due later.
+func (s *Server) queue(service string, name string, message []byte, at time.Time) error {
+	if !at.After(time.Now()) {
+		return s.messager.Queue(service, name, message)
+	}
+	scheduler, ok := s.messager.(messaging.Scheduler)
+	if !ok {
+		return errScheduleNotSupported
+	}
+	return scheduler.QueueAt(service, name, message, at)
+}
+
+// publish holds the message back if it is due later.
+func (s *Server) publish(service string, name string, message []byte, at time.Time) error {
+	if !at.After(time.Now()) {
+		return s.messager.Publish(service, name, message)
+	}
+	scheduler, ok := s.messager.(messaging.Scheduler)
+	if !ok {
+		return errScheduleNotSupported
+	}
+	return scheduler.PublishAt(service, name, message, at)
+}
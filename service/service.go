@@ -5,31 +5,38 @@ import (
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"github.com/turgayozgur/messageman/config"
+	"github.com/turgayozgur/messageman/internal/jobs"
 	"github.com/turgayozgur/messageman/internal/messaging"
 	"github.com/turgayozgur/messageman/internal/metrics"
 	pb "github.com/turgayozgur/messageman/pb/v1/gen"
 	"github.com/valyala/fasthttp"
 	"google.golang.org/grpc"
 	"net"
+	"strings"
 )
 
 // Server contains all that is needed to respond to incoming requests, like a database. Other services like a mail
 type Server struct {
 	pb.UnimplementedJobDispatcherServiceServer
 	pb.UnimplementedPublisherServiceServer
-	messager messaging.Messager
-	wrapper  messaging.Wrapper
-	exporter metrics.Exporter
-	mainAPI  string
+	pb.UnimplementedDeadLetterServiceServer
+	messager  messaging.Messager
+	wrapper   messaging.Wrapper
+	exporter  metrics.Exporter
+	jobs      jobs.Store
+	requester *messaging.Requester
+	mainAPI   string
 }
 
 // NewServer initializes the service with the given Database, and sets up appropriate routes.
-func NewServer(messager messaging.Messager, wrapper messaging.Wrapper, exporter metrics.Exporter, mainAPI string) *Server {
+func NewServer(messager messaging.Messager, wrapper messaging.Wrapper, exporter metrics.Exporter, store jobs.Store, requester *messaging.Requester, mainAPI string) *Server {
 	server := &Server{
-		messager: messager,
-		wrapper:  wrapper,
-		exporter: exporter,
-		mainAPI:  mainAPI,
+		messager:  messager,
+		wrapper:   wrapper,
+		exporter:  exporter,
+		jobs:      store,
+		requester: requester,
+		mainAPI:   mainAPI,
 	}
 	return server
 }
@@ -43,6 +50,7 @@ func (s *Server) Listen() {
 	gSrv := grpc.NewServer()
 	pb.RegisterJobDispatcherServiceServer(gSrv, s)
 	pb.RegisterPublisherServiceServer(gSrv, s)
+	pb.RegisterDeadLetterServiceServer(gSrv, s)
 	go func() {
 		log.Info().Msgf("now, gRPC listening on: http://localhost:%s", config.Cfg.GRPCPort)
 		if err := gSrv.Serve(lis); err != nil {
@@ -67,9 +75,31 @@ func (s *Server) Listen() {
 			s.QueueREST(ctx)
 		case "/v1/publish":
 			s.PublishREST(ctx)
+		case "/v1/request":
+			s.RequestREST(ctx)
+		case "/v1/queue/batch":
+			s.QueueBatchREST(ctx)
+		case "/v1/publish/batch":
+			s.PublishBatchREST(ctx)
+		case "/v1/deadletters":
+			s.DeadLetterQueuesREST(ctx)
+		case "/v1/deadletters/peek":
+			s.PeekDeadLettersREST(ctx)
+		case "/v1/deadletters/replay":
+			s.ReplayDeadLetterREST(ctx)
+		case "/v1/deadletters/purge":
+			s.PurgeDeadLettersREST(ctx)
 		case "/metrics":
 			s.exporter.Handle(ctx)
 		default:
+			if id := strings.TrimPrefix(string(ctx.Path()), "/v1/jobs/"); len(id) < len(ctx.Path()) {
+				if ctx.IsDelete() {
+					s.CancelJobREST(ctx, id)
+					return
+				}
+				s.GetJobREST(ctx, id)
+				return
+			}
 			s.notFound(ctx)
 		}
 	}
@@ -123,6 +153,18 @@ func (s *Server) notFound(ctx *fasthttp.RequestCtx) {
 	s.error(ctx, fasthttp.StatusNotFound, "404")
 }
 
+// allowMethod writes a 405 if the request method is not one of the given methods.
+func (s *Server) allowMethod(ctx *fasthttp.RequestCtx, methods ...string) bool {
+	for _, m := range methods {
+		if string(ctx.Method()) == m {
+			return true
+		}
+	}
+	ctx.Response.Header.Set(fasthttp.HeaderAllow, strings.Join(methods, ", "))
+	s.error(ctx, fasthttp.StatusMethodNotAllowed, "405")
+	return false
+}
+
 // ResponseModel is returned by our service when an error occurs.
 type ResponseModel struct {
 	Message string `json:"message"`